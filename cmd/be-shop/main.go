@@ -4,11 +4,15 @@ import (
 	"be-shop/internal/app"
 	"be-shop/internal/app/controller"
 	"be-shop/internal/app/infra"
+	"be-shop/internal/app/repo"
+	"be-shop/internal/app/repo/memory"
 	"be-shop/internal/app/repo/postgres"
 	"be-shop/internal/app/service"
+	"be-shop/internal/app/service/gateway"
 	"be-shop/internal/app/service/utils"
 	"be-shop/pkg/di"
 	"be-shop/pkg/middleware"
+	"database/sql"
 	"fmt"
 	"log/slog"
 
@@ -70,6 +74,31 @@ func LoadApplicationConfig() error {
 	if err != nil {
 		return fmt.Errorf("LoadJwtCfg: %s", err.Error())
 	}
+
+	err = di.Provide(infra.LoadOrderCfg)
+	if err != nil {
+		return fmt.Errorf("LoadOrderCfg: %s", err.Error())
+	}
+
+	err = di.Provide(infra.LoadPaymentCfg)
+	if err != nil {
+		return fmt.Errorf("LoadPaymentCfg: %s", err.Error())
+	}
+
+	err = di.Provide(infra.LoadIdempotencyCfg)
+	if err != nil {
+		return fmt.Errorf("LoadIdempotencyCfg: %s", err.Error())
+	}
+
+	err = di.Provide(infra.LoadMailCfg)
+	if err != nil {
+		return fmt.Errorf("LoadMailCfg: %s", err.Error())
+	}
+
+	err = di.Provide(infra.LoadAuthCfg)
+	if err != nil {
+		return fmt.Errorf("LoadAuthCfg: %s", err.Error())
+	}
 	return nil
 }
 
@@ -79,6 +108,16 @@ func LoadApplicationPackage() error {
 		return fmt.Errorf("NewEcho: %s", err.Error())
 	}
 
+	err = di.Provide(utils.NewKeyManager)
+	if err != nil {
+		return fmt.Errorf("NewKeyManager: %s", err.Error())
+	}
+
+	err = di.Provide(infra.NewMailer)
+	if err != nil {
+		return fmt.Errorf("NewMailer: %s", err.Error())
+	}
+
 	err = di.Provide(infra.NewDatabases)
 	if err != nil {
 		fmt.Println("NewDatabases: ", err.Error())
@@ -109,11 +148,57 @@ func LoadApplicationRepository() error {
 	if err != nil {
 		return fmt.Errorf("NewCategoryRepo: %s", err.Error())
 	}
+	err = di.Provide(postgres.NewOrderRepo)
+	if err != nil {
+		return fmt.Errorf("NewOrderRepo: %s", err.Error())
+	}
+	err = di.Provide(postgres.NewIdempotencyRepo)
+	if err != nil {
+		return fmt.Errorf("NewIdempotencyRepo: %s", err.Error())
+	}
+	err = di.Provide(postgres.NewPromotionRepo)
+	if err != nil {
+		return fmt.Errorf("NewPromotionRepo: %s", err.Error())
+	}
+	err = di.Provide(postgres.NewRefreshTokenRepo)
+	if err != nil {
+		return fmt.Errorf("NewRefreshTokenRepo: %s", err.Error())
+	}
+	err = di.Provide(postgres.NewUserTokenRepo)
+	if err != nil {
+		return fmt.Errorf("NewUserTokenRepo: %s", err.Error())
+	}
+	err = di.Provide(postgres.NewAddressRepo)
+	if err != nil {
+		return fmt.Errorf("NewAddressRepo: %s", err.Error())
+	}
+	err = di.Provide(memory.NewLoginAttemptStore)
+	if err != nil {
+		return fmt.Errorf("NewLoginAttemptStore: %s", err.Error())
+	}
+	err = di.Provide(NewRevocationStore)
+	if err != nil {
+		return fmt.Errorf("NewRevocationStore: %s", err.Error())
+	}
 	return nil
 }
 
+// NewRevocationStore picks the token revocation store named by
+// JWT_REVOCATION_STORE.
+func NewRevocationStore(cfg *infra.JwtCfg, db *sql.DB) repo.RevocationStore {
+	if cfg.RevocationStore == "memory" {
+		return memory.NewRevocationStore()
+	}
+	return postgres.NewRevocationStore(postgres.RevocationStoreImpl{DB: db})
+}
+
 func LoadApplicationService() error {
-	err := di.Provide(service.NewUserSvc)
+	err := di.Provide(gateway.NewRegistry)
+	if err != nil {
+		return fmt.Errorf("NewRegistry: %s", err.Error())
+	}
+
+	err = di.Provide(service.NewUserSvc)
 	if err != nil {
 		return fmt.Errorf("NewUserSvc: %s", err.Error())
 	}
@@ -133,6 +218,31 @@ func LoadApplicationService() error {
 		return fmt.Errorf("NewPaymentSvc: %s", err.Error())
 	}
 
+	err = di.Provide(service.NewOrderSvc)
+	if err != nil {
+		return fmt.Errorf("NewOrderSvc: %s", err.Error())
+	}
+
+	err = di.Provide(service.NewOrderExpiryWorker)
+	if err != nil {
+		return fmt.Errorf("NewOrderExpiryWorker: %s", err.Error())
+	}
+
+	err = di.Provide(service.NewIdempotencyCleanupWorker)
+	if err != nil {
+		return fmt.Errorf("NewIdempotencyCleanupWorker: %s", err.Error())
+	}
+
+	err = di.Provide(service.NewPromotionSvc)
+	if err != nil {
+		return fmt.Errorf("NewPromotionSvc: %s", err.Error())
+	}
+
+	err = di.Provide(service.NewAddressSvc)
+	if err != nil {
+		return fmt.Errorf("NewAddressSvc: %s", err.Error())
+	}
+
 	return nil
 }
 
@@ -161,5 +271,20 @@ func LoadApplicationController() error {
 		return fmt.Errorf("NewPaymentCtrl: %s", err.Error())
 	}
 
+	err = di.Provide(controller.NewOrderCtrl)
+	if err != nil {
+		return fmt.Errorf("NewOrderCtrl: %s", err.Error())
+	}
+
+	err = di.Provide(controller.NewPromotionCtrl)
+	if err != nil {
+		return fmt.Errorf("NewPromotionCtrl: %s", err.Error())
+	}
+
+	err = di.Provide(controller.NewAddressCtrl)
+	if err != nil {
+		return fmt.Errorf("NewAddressCtrl: %s", err.Error())
+	}
+
 	return nil
 }
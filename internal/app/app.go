@@ -2,6 +2,7 @@ package app
 
 import (
 	"be-shop/internal/app/infra"
+	"be-shop/internal/app/service"
 	"be-shop/pkg/di"
 	"context"
 	"database/sql"
@@ -37,11 +38,16 @@ func Start() {
 func startApp(
 	e *echo.Echo,
 	appCfg *infra.AppCfg,
+	orderExpiryWorker service.OrderExpiryWorker,
+	idempotencyCleanupWorker service.IdempotencyCleanupWorker,
 ) error {
 	if err := di.Invoke(setRoute); err != nil {
 		return err
 	}
 
+	orderExpiryWorker.Start()
+	idempotencyCleanupWorker.Start()
+
 	return e.StartServer(&http.Server{
 		Addr:         appCfg.Address,
 		ReadTimeout:  appCfg.ReadTimeout,
@@ -52,6 +58,8 @@ func startApp(
 func gracefulShutdown(
 	e *echo.Echo,
 	pg *sql.DB,
+	orderExpiryWorker service.OrderExpiryWorker,
+	idempotencyCleanupWorker service.IdempotencyCleanupWorker,
 ) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
@@ -59,6 +67,14 @@ func gracefulShutdown(
 
 	log.Info().Msg("shutting down server")
 
+	if err := orderExpiryWorker.Stop(ctx); err != nil {
+		log.Error().Msgf("order expiry worker stop: %s", err.Error())
+	}
+
+	if err := idempotencyCleanupWorker.Stop(ctx); err != nil {
+		log.Error().Msgf("idempotency cleanup worker stop: %s", err.Error())
+	}
+
 	if err := pg.Close(); err != nil {
 		log.Error().Msgf("postgres close: %s", err.Error())
 	}
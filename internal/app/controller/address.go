@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/service"
+	"be-shop/internal/app/service/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/dig"
+)
+
+type (
+	AddressCtrl interface {
+		CreateAddress(ec echo.Context) error
+		GetAddresses(ec echo.Context) error
+		UpdateAddress(ec echo.Context) error
+		DeleteAddress(ec echo.Context) error
+		SetDefaultAddress(ec echo.Context) error
+	}
+
+	AddressCtrlImpl struct {
+		dig.In
+
+		AddressSvc service.AddressSvc
+	}
+)
+
+func NewAddressCtrl(impl AddressCtrlImpl) AddressCtrl {
+	return &impl
+}
+
+func (a *AddressCtrlImpl) CreateAddress(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.AddressReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.CreateAddress] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.CreateAddress] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := a.AddressSvc.CreateAddress(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.CreateAddress] error while CreateAddress err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (a *AddressCtrlImpl) GetAddresses(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	resp, err := a.AddressSvc.GetAddresses(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.GetAddresses] error while GetAddresses err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (a *AddressCtrlImpl) UpdateAddress(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id, err := strconv.Atoi(ec.Param("id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.UpdateAddress] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	var req service.AddressReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.UpdateAddress] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err = validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.UpdateAddress] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := a.AddressSvc.UpdateAddress(ctx, id, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.UpdateAddress] error while UpdateAddress err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (a *AddressCtrlImpl) DeleteAddress(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id, err := strconv.Atoi(ec.Param("id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.DeleteAddress] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	resp, err := a.AddressSvc.DeleteAddress(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.DeleteAddress] error while DeleteAddress err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (a *AddressCtrlImpl) SetDefaultAddress(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id, err := strconv.Atoi(ec.Param("id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.SetDefaultAddress] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	resp, err := a.AddressSvc.SetDefaultAddress(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressCtrl.SetDefaultAddress] error while SetDefaultAddress err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
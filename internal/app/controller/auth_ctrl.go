@@ -4,8 +4,11 @@ import (
 	"be-shop/internal/app/models"
 	"be-shop/internal/app/service"
 	"be-shop/internal/app/service/utils"
+	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -29,12 +32,28 @@ type (
 	AuthCtrl interface {
 		UserRegistration(ec echo.Context) error
 		UserLogin(ec echo.Context) error
+		RefreshToken(ec echo.Context) error
+		Logout(ec echo.Context) error
+		LogoutAll(ec echo.Context) error
+		VerifyEmail(ec echo.Context) error
+		ResendVerification(ec echo.Context) error
+		ForgotPassword(ec echo.Context) error
+		ResetPassword(ec echo.Context) error
+		UpdateUserRole(ec echo.Context) error
+		UnlockUser(ec echo.Context) error
+		GetProfile(ec echo.Context) error
+		UpdateProfile(ec echo.Context) error
+		ChangePassword(ec echo.Context) error
+		ChangeEmail(ec echo.Context) error
+		ConfirmEmailChange(ec echo.Context) error
+		JWKS(ec echo.Context) error
 	}
 
 	AuthCtrlImpl struct {
 		dig.In
 
-		UserSvc service.UserSvc
+		UserSvc    service.UserSvc
+		KeyManager *utils.KeyManager
 	}
 )
 
@@ -117,12 +136,451 @@ func (ox *AuthCtrlImpl) UserLogin(ec echo.Context) error {
 		})
 	}
 
+	user.IP = ec.RealIP()
+
 	res, err := ox.UserSvc.UserLogin(ctx, user)
 	if err != nil {
 		slog.Error("UserLogin - something went wrong", err)
-		return ec.JSON(http.StatusBadRequest, res)
+		setRetryAfter(ec, err)
+		return ec.JSON(res.Code, res)
 	}
 
 	return ec.JSON(http.StatusOK, res)
 
 }
+
+func (ox *AuthCtrlImpl) RefreshToken(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.RefreshTokenReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.RefreshToken] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.RefreshToken] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.RefreshToken(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.RefreshToken] error while RefreshToken err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) Logout(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.LogoutReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.Logout] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.Logout(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.Logout] error while Logout err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) LogoutAll(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	resp, err := ox.UserSvc.LogoutAll(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.LogoutAll] error while LogoutAll err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) UpdateUserRole(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id, err := strconv.Atoi(ec.Param("id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UpdateUserRole] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	var req service.UpdateRoleReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UpdateUserRole] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err = validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UpdateUserRole] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.UpdateUserRole(ctx, id, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UpdateUserRole] error while UpdateUserRole err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+// JWKS publishes the public keys that verify our access tokens.
+func (ox *AuthCtrlImpl) JWKS(ec echo.Context) error {
+	ec.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return ec.JSON(http.StatusOK, ox.KeyManager.JWKS())
+}
+
+func (ox *AuthCtrlImpl) ForgotPassword(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.ForgotPasswordReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ForgotPassword] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ForgotPassword] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.ForgotPassword(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ForgotPassword] error while ForgotPassword err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) ResetPassword(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.ResetPasswordReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ResetPassword] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ResetPassword] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.ResetPassword(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ResetPassword] error while ResetPassword err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) VerifyEmail(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.VerifyEmailReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.VerifyEmail] Invalid request", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.VerifyEmail] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.VerifyEmail(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.VerifyEmail] error while VerifyEmail err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) ResendVerification(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	resp, err := ox.UserSvc.ResendVerification(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ResendVerification] error while ResendVerification err", "%v", err.Error())
+		setRetryAfter(ec, err)
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+// setRetryAfter sets the Retry-After header, in whole seconds, when err is a
+// service.RetryAfterError.
+func setRetryAfter(ec echo.Context, err error) {
+	var retryErr *service.RetryAfterError
+	if errors.As(err, &retryErr) {
+		seconds := int(math.Ceil(retryErr.RetryAfter.Seconds()))
+		ec.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+}
+
+func (ox *AuthCtrlImpl) UnlockUser(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id, err := strconv.Atoi(ec.Param("id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UnlockUser] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.UnlockUser(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UnlockUser] error while UnlockUser err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) GetProfile(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	resp, err := ox.UserSvc.GetProfile(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.GetProfile] error while GetProfile err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) UpdateProfile(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.UpdateProfileReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UpdateProfile] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UpdateProfile] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.UpdateProfile(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.UpdateProfile] error while UpdateProfile err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) ChangePassword(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.ChangePasswordReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ChangePassword] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ChangePassword] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.ChangePassword(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ChangePassword] error while ChangePassword err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) ChangeEmail(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.ChangeEmailReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ChangeEmail] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ChangeEmail] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.ChangeEmail(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ChangeEmail] error while ChangeEmail err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (ox *AuthCtrlImpl) ConfirmEmailChange(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.VerifyEmailReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ConfirmEmailChange] Invalid request", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ConfirmEmailChange] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := ox.UserSvc.ConfirmEmailChange(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AuthCtrl.ConfirmEmailChange] error while ConfirmEmailChange err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
@@ -20,6 +20,8 @@ type (
 		UpdateCartQuantity(ec echo.Context) error
 		DeleteCart(ec echo.Context) error
 		DeleteAllCart(ec echo.Context) error
+		ApplyCoupon(ec echo.Context) error
+		RemoveCoupon(ec echo.Context) error
 	}
 
 	CartCtrlImpl struct {
@@ -173,3 +175,52 @@ func (m *CartCtrlImpl) DeleteAllCart(ec echo.Context) error {
 
 	return ec.JSON(resp.Code, resp)
 }
+
+func (m *CartCtrlImpl) ApplyCoupon(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.ApplyCouponReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[CartCtrl.ApplyCoupon] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartCtrl.ApplyCoupon] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := m.CartSvc.ApplyCoupon(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartCtrl.ApplyCoupon] error while ApplyCoupon err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *CartCtrlImpl) RemoveCoupon(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	resp, err := m.CartSvc.RemoveCoupon(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartCtrl.RemoveCoupon] error while RemoveCoupon err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
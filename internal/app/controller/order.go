@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/service"
+	"be-shop/internal/app/service/utils"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/dig"
+)
+
+type (
+	OrderCtrl interface {
+		GetOrders(ec echo.Context) error
+		GetOrderDetail(ec echo.Context) error
+		CancelOrder(ec echo.Context) error
+		GetAllOrders(ec echo.Context) error
+		UpdateOrderStatus(ec echo.Context) error
+	}
+
+	OrderCtrlImpl struct {
+		dig.In
+
+		OrderSvc service.OrderSvc
+	}
+)
+
+func NewOrderCtrl(impl OrderCtrlImpl) OrderCtrl {
+	return &impl
+}
+
+func (o *OrderCtrlImpl) GetOrders(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.GetOrdersReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.GetOrders] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+	req.UseCursor = ec.QueryParams().Has("cursor")
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.GetOrders] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	switch {
+	case req.Page == 0 && req.Limit == 0:
+		req.SetDefaults()
+	case req.Page == 0:
+		req.SetDefaultPage()
+	case req.Limit == 0:
+		req.SetDefaultLimit()
+	}
+
+	resp, err := o.OrderSvc.GetOrders(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.GetOrders] error while GetOrders err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (o *OrderCtrlImpl) GetOrderDetail(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	resp, err := o.OrderSvc.GetOrderDetail(ctx, ec.Param("order_code"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.GetOrderDetail] error while GetOrderDetail err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (o *OrderCtrlImpl) CancelOrder(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.CancelOrderReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.CancelOrder] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.CancelOrder] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := o.OrderSvc.CancelOrder(ctx, ec.Param("order_code"), req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.CancelOrder] error while CancelOrder err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (o *OrderCtrlImpl) GetAllOrders(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.AdminGetOrdersReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.GetAllOrders] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+	req.UseCursor = ec.QueryParams().Has("cursor")
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.GetAllOrders] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	switch {
+	case req.Page == 0 && req.Limit == 0:
+		req.SetDefaults()
+	case req.Page == 0:
+		req.SetDefaultPage()
+	case req.Limit == 0:
+		req.SetDefaultLimit()
+	}
+
+	resp, err := o.OrderSvc.GetAllOrders(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.GetAllOrders] error while GetAllOrders err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (o *OrderCtrlImpl) UpdateOrderStatus(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.UpdateOrderStatusReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.UpdateOrderStatus] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.UpdateOrderStatus] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := o.OrderSvc.UpdateOrderStatus(ctx, ec.Param("order_code"), req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderCtrl.UpdateOrderStatus] error while UpdateOrderStatus err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
@@ -4,6 +4,7 @@ import (
 	"be-shop/internal/app/models"
 	"be-shop/internal/app/service"
 	"be-shop/internal/app/service/utils"
+	"io"
 	"log/slog"
 	"net/http"
 
@@ -16,6 +17,7 @@ type (
 	PaymentCtrl interface {
 		Checkout(ec echo.Context) error
 		SimulatePayment(ec echo.Context) error
+		Webhook(ec echo.Context) error
 	}
 
 	PaymentCtrlImpl struct {
@@ -33,7 +35,30 @@ func (p *PaymentCtrlImpl) Checkout(ec echo.Context) error {
 	Recover()
 	ctx := ec.Request().Context()
 
-	resp, err := p.PaymentSvc.CreatePayment(ctx)
+	var req service.CheckoutReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[PaymentCtrl.Checkout] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentCtrl.Checkout] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := p.PaymentSvc.CreatePayment(ctx, req)
 	if err != nil {
 		slog.ErrorContext(ctx, "[PaymentCtrl.Checkout] error while CreatePayment err", "%v", err.Error())
 		return ec.JSON(resp.Code, resp)
@@ -78,3 +103,28 @@ func (p *PaymentCtrlImpl) SimulatePayment(ec echo.Context) error {
 
 	return ec.JSON(resp.Code, resp)
 }
+
+func (p *PaymentCtrlImpl) Webhook(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	// the signature covers the exact bytes the provider sent, so the body
+	// is read raw instead of bound
+	body, err := io.ReadAll(ec.Request().Body)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentCtrl.Webhook] error while reading body err", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	resp, err := p.PaymentSvc.HandleWebhook(ctx, ec.Param("provider"), ec.Request().Header, body)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentCtrl.Webhook] error while HandleWebhook err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
@@ -19,10 +19,19 @@ type (
 		GetProductByID(ec echo.Context) error
 		GetProductsByCategoryID(ec echo.Context) error
 		GetAllProduct(ec echo.Context) error
+		SearchProducts(ec echo.Context) error
 		UpdateProductPrice(ec echo.Context) error
 		DeleteProduct(ec echo.Context) error
+		RestoreProduct(ec echo.Context) error
+		GetDeletedProducts(ec echo.Context) error
+		AdjustProductStock(ec echo.Context) error
+		GetStockAdjustments(ec echo.Context) error
 
+		GetCategories(ec echo.Context) error
 		CreateCategory(ec echo.Context) error
+		RenameCategory(ec echo.Context) error
+		MoveCategory(ec echo.Context) error
+		DeleteCategory(ec echo.Context) error
 	}
 
 	ProductCtrlImpl struct {
@@ -94,11 +103,7 @@ func (m *ProductCtrlImpl) GetProductByID(ec echo.Context) error {
 	resp, err := m.ProductSvc.GetProductByID(ctx, idConv)
 	if err != nil {
 		slog.Error("GetProductByID - error while getting product", err)
-		return ec.JSON(http.StatusInternalServerError, models.DefaultResponse{
-			Code:    resp.Code,
-			Message: "Failed to get product",
-			Error:   err.(validator.ValidationErrors).Error(),
-		})
+		return ec.JSON(resp.Code, resp)
 	}
 
 	return ec.JSON(resp.Code, resp)
@@ -108,7 +113,7 @@ func (m *ProductCtrlImpl) GetAllProduct(ec echo.Context) error {
 	Recover()
 	ctx := ec.Request().Context()
 
-	var req models.PaginationRequest
+	var req service.GetProductsReq
 
 	if err := ec.Bind(&req); err != nil {
 		slog.Error("GetAllProduct - Invalid request body", err)
@@ -118,6 +123,20 @@ func (m *ProductCtrlImpl) GetAllProduct(ec echo.Context) error {
 			Error:   err.Error(),
 		})
 	}
+	req.UseCursor = ec.QueryParams().Has("cursor")
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.GetAllProduct] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
 
 	switch {
 	case req.Page == 0 && req.Limit == 0:
@@ -131,13 +150,55 @@ func (m *ProductCtrlImpl) GetAllProduct(ec echo.Context) error {
 	resp, err := m.ProductSvc.GetAllProduct(ctx, req)
 	if err != nil {
 		slog.Error("GetAllProduct - error while getting all products", err)
-		return ec.JSON(http.StatusInternalServerError, models.DefaultResponse{
-			Code:    resp.Code,
-			Message: "Failed to get products",
-			Error:   err.(validator.ValidationErrors).Error(),
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) SearchProducts(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.SearchProductsReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.SearchProducts] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
 		})
 	}
 
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.SearchProducts] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	switch {
+	case req.Page == 0 && req.Limit == 0:
+		req.SetDefaults()
+	case req.Page == 0:
+		req.SetDefaultPage()
+	case req.Limit == 0:
+		req.SetDefaultLimit()
+	}
+
+	resp, err := m.ProductSvc.SearchProducts(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.SearchProducts] error while SearchProducts err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
 	return ec.JSON(resp.Code, resp)
 }
 
@@ -214,13 +275,151 @@ func (m *ProductCtrlImpl) DeleteProduct(ec echo.Context) error {
 	resp, err := m.ProductSvc.DeleteProduct(ctx, idConv)
 	if err != nil {
 		slog.Error("DeleteProduct - error while deleting product", err)
-		return ec.JSON(http.StatusInternalServerError, models.DefaultResponse{
-			Code:    resp.Code,
-			Message: "Failed to delete product",
-			Error:   err.(validator.ValidationErrors).Error(),
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) RestoreProduct(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id := ec.Param("id")
+
+	idConv, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.RestoreProduct] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	resp, err := m.ProductSvc.RestoreProduct(ctx, idConv)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.RestoreProduct] error while RestoreProduct err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) GetDeletedProducts(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.GetProductsReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.GetDeletedProducts] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+	req.UseCursor = ec.QueryParams().Has("cursor")
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.GetDeletedProducts] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
 		})
 	}
 
+	switch {
+	case req.Page == 0 && req.Limit == 0:
+		req.SetDefaults()
+	case req.Page == 0:
+		req.SetDefaultPage()
+	case req.Limit == 0:
+		req.SetDefaultLimit()
+	}
+
+	resp, err := m.ProductSvc.GetDeletedProducts(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.GetDeletedProducts] error while GetDeletedProducts err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) AdjustProductStock(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	idConv, err := strconv.ParseInt(ec.Param("id"), 10, 64)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.AdjustProductStock] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	var req service.AdjustStockReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.AdjustProductStock] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err = validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.AdjustProductStock] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := m.ProductSvc.AdjustProductStock(ctx, idConv, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.AdjustProductStock] error while AdjustProductStock err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) GetStockAdjustments(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	idConv, err := strconv.ParseInt(ec.Param("id"), 10, 64)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.GetStockAdjustments] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	resp, err := m.ProductSvc.GetStockAdjustments(ctx, idConv)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.GetStockAdjustments] error while GetStockAdjustments err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
 	return ec.JSON(resp.Code, resp)
 }
 
@@ -243,11 +442,7 @@ func (m *ProductCtrlImpl) GetProductsByCategoryID(ec echo.Context) error {
 	resp, err := m.ProductSvc.GetProductByCategoryID(ctx, idConv)
 	if err != nil {
 		slog.Error("GetProductsByCategoryID - error while getting product by category id", err)
-		return ec.JSON(http.StatusInternalServerError, models.DefaultResponse{
-			Code:    resp.Code,
-			Message: "Failed to get product by category id",
-			Error:   err.(validator.ValidationErrors).Error(),
-		})
+		return ec.JSON(resp.Code, resp)
 	}
 
 	return ec.JSON(resp.Code, resp)
@@ -281,15 +476,141 @@ func (m *ProductCtrlImpl) CreateCategory(ec echo.Context) error {
 		})
 	}
 
-	resp, err := m.ProductSvc.CreateCategory(ctx, req.Name)
+	resp, err := m.ProductSvc.CreateCategory(ctx, req)
 	if err != nil {
 		slog.Error("CreateCategory - error while creating category", err)
-		return ec.JSON(http.StatusInternalServerError, models.DefaultResponse{
-			Code:    resp.Code,
-			Message: "Failed to create category",
-			Error:   err.(validator.ValidationErrors).Error(),
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) GetCategories(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	resp, err := m.ProductSvc.GetCategories(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.GetCategories] error while GetCategories err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) RenameCategory(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id, err := strconv.Atoi(ec.Param("id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.RenameCategory] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	var req service.RenameCategoryReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.RenameCategory] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
 		})
 	}
 
+	validate := utils.Validate
+
+	err = validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.RenameCategory] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := m.ProductSvc.RenameCategory(ctx, id, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.RenameCategory] error while RenameCategory err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) MoveCategory(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id, err := strconv.Atoi(ec.Param("id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.MoveCategory] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	var req service.MoveCategoryReq
+
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.MoveCategory] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err = validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.MoveCategory] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := m.ProductSvc.MoveCategory(ctx, id, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.MoveCategory] error while MoveCategory err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (m *ProductCtrlImpl) DeleteCategory(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	id, err := strconv.Atoi(ec.Param("id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.DeleteCategory] error while converting id", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	resp, err := m.ProductSvc.DeleteCategory(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductCtrl.DeleteCategory] error while DeleteCategory err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
 	return ec.JSON(resp.Code, resp)
 }
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/service"
+	"be-shop/internal/app/service/utils"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/dig"
+)
+
+type (
+	PromotionCtrl interface {
+		CreatePromotion(ec echo.Context) error
+		GetPromotions(ec echo.Context) error
+	}
+
+	PromotionCtrlImpl struct {
+		dig.In
+
+		PromotionSvc service.PromotionSvc
+	}
+)
+
+func NewPromotionCtrl(impl PromotionCtrlImpl) PromotionCtrl {
+	return &impl
+}
+
+func (p *PromotionCtrlImpl) CreatePromotion(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req service.CreatePromotionReq
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[PromotionCtrl.CreatePromotion] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	validate := utils.Validate
+
+	err := validate.Struct(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionCtrl.CreatePromotion] validation error", "%v", err.Error())
+		errors := err.(validator.ValidationErrors)
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   errors.Error(),
+		})
+	}
+
+	resp, err := p.PromotionSvc.CreatePromotion(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionCtrl.CreatePromotion] error while CreatePromotion err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
+
+func (p *PromotionCtrlImpl) GetPromotions(ec echo.Context) error {
+	Recover()
+	ctx := ec.Request().Context()
+
+	var req models.PaginationRequest
+	if err := ec.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, "[PromotionCtrl.GetPromotions] Invalid request body", "%v", err.Error())
+		return ec.JSON(http.StatusBadRequest, models.DefaultResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	switch {
+	case req.Page == 0 && req.Limit == 0:
+		req.SetDefaults()
+	case req.Page == 0:
+		req.SetDefaultPage()
+	case req.Limit == 0:
+		req.SetDefaultLimit()
+	}
+
+	resp, err := p.PromotionSvc.GetPromotions(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionCtrl.GetPromotions] error while GetPromotions err", "%v", err.Error())
+		return ec.JSON(resp.Code, resp)
+	}
+
+	return ec.JSON(resp.Code, resp)
+}
@@ -0,0 +1,38 @@
+package infra
+
+import "time"
+
+type (
+	AuthCfg struct {
+		PasswordResetTTL time.Duration `envconfig:"PASSWORD_RESET_TTL" default:"1h"`
+		// PasswordResetURL is the frontend page that receives ?token=.
+		PasswordResetURL string `envconfig:"PASSWORD_RESET_URL" default:"http://localhost:3000/reset-password"`
+
+		EmailVerificationTTL time.Duration `envconfig:"EMAIL_VERIFICATION_TTL" default:"24h"`
+		// EmailVerificationURL receives ?token=; by default it is our own
+		// GET /v1/users/verify.
+		EmailVerificationURL string `envconfig:"EMAIL_VERIFICATION_URL" default:"http://localhost:8089/v1/users/verify"`
+		// EmailChangeURL receives ?token= from the link mailed to a new
+		// address; by default it is our own GET /v1/users/email/confirm.
+		EmailChangeURL string `envconfig:"EMAIL_CHANGE_URL" default:"http://localhost:8089/v1/users/email/confirm"`
+		// VerificationResendInterval is the minimum time between two
+		// verification emails to the same account.
+		VerificationResendInterval time.Duration `envconfig:"VERIFICATION_RESEND_INTERVAL" default:"1m"`
+		// RequireVerifiedEmailForCheckout rejects checkout with 403 until the
+		// user has verified their email address.
+		RequireVerifiedEmailForCheckout bool `envconfig:"REQUIRE_VERIFIED_EMAIL_FOR_CHECKOUT" default:"false"`
+
+		// Failed logins are counted per account and per IP address and
+		// forgotten after LoginWindow without failures. From
+		// LoginDelayAfter failures on, each retry must wait LoginDelay,
+		// doubling per failure up to LoginMaxDelay. LoginMaxFailures locks
+		// the account and LoginMaxIPFailures blocks the IP for LoginLockout.
+		LoginWindow        time.Duration `envconfig:"LOGIN_WINDOW" default:"15m"`
+		LoginDelayAfter    int           `envconfig:"LOGIN_DELAY_AFTER" default:"3"`
+		LoginDelay         time.Duration `envconfig:"LOGIN_DELAY" default:"1s"`
+		LoginMaxDelay      time.Duration `envconfig:"LOGIN_MAX_DELAY" default:"30s"`
+		LoginMaxFailures   int           `envconfig:"LOGIN_MAX_FAILURES" default:"10"`
+		LoginMaxIPFailures int           `envconfig:"LOGIN_MAX_IP_FAILURES" default:"50"`
+		LoginLockout       time.Duration `envconfig:"LOGIN_LOCKOUT" default:"15m"`
+	}
+)
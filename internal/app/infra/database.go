@@ -5,12 +5,22 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
 	"go.uber.org/dig"
 )
 
+const (
+	// SampleJwtSecretKey is the JWT_SECRET_KEY default. It is public, so
+	// HS256 tokens signed with it could be forged by anyone.
+	SampleJwtSecretKey = "secret"
+	// MinJwtSecretLength is the shortest JWT_SECRET_KEY accepted, the size
+	// of an HS256 digest.
+	MinJwtSecretLength = 32
+)
+
 type (
 	Databases struct {
 		dig.Out
@@ -35,9 +45,35 @@ type (
 	}
 
 	JwtCfg struct {
-		SecretKey string `envconfig:"JWT_SECRET_KEY" required:"true" default:"secret"`
+		// SecretKey signs HS256 tokens while Keys is empty. It must be at
+		// least MinJwtSecretLength bytes and not the SampleJwtSecretKey default.
+		SecretKey       string        `envconfig:"SECRET_KEY" required:"true" default:"secret"`
+		EncryptKey      string        `envconfig:"ENCRYPT_KEY"`
+		EncryptIV       string        `envconfig:"ENCRYPT_IV"`
+		Issuer          string        `envconfig:"ISSUER"`
+		AccessTokenTTL  time.Duration `envconfig:"ACCESS_TOKEN_TTL" default:"15m"`
+		RefreshTokenTTL time.Duration `envconfig:"REFRESH_TOKEN_TTL" default:"720h"`
+
+		// Keys maps key ids to PEM files, e.g. "2024a:/keys/a.pem,2024b:/keys/b.pem".
+		// RSA keys sign with RS256 and Ed25519 keys with EdDSA. A file holding
+		// only a public key can verify but never sign.
+		Keys        map[string]string `envconfig:"KEYS"`
+		ActiveKeyID string            `envconfig:"ACTIVE_KEY_ID"`
+		// RetiredKeys maps key ids to the RFC 3339 time they stopped signing.
+		// They keep verifying for RotationOverlap, which is never shorter
+		// than AccessTokenTTL.
+		RetiredKeys     KeyTimes      `envconfig:"RETIRED_KEYS"`
+		RotationOverlap time.Duration `envconfig:"ROTATION_OVERLAP" default:"1h"`
+		// RevocationStore is "postgres" or "memory". The memory store is not
+		// shared between instances and forgets revocations on restart.
+		RevocationStore string `envconfig:"REVOCATION_STORE" default:"postgres"`
 	}
 
+	// KeyTimes decodes "id:time,id:time". Unlike envconfig's own map
+	// decoding it only splits on the first colon, so the times may be
+	// written in RFC 3339.
+	KeyTimes map[string]string
+
 	DiscordCfg struct {
 		DiscordToken   string `envconfig:"DISCORD_TOKEN" required:"true" default:"discord_token"`
 		DiscordGuildID string `envconfig:"DISCORD_GUILD_ID" required:"true" default:"discord_guild_id"`
@@ -45,6 +81,22 @@ type (
 	}
 )
 
+func (k *KeyTimes) Decode(value string) error {
+	times := make(KeyTimes)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		id, at, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return fmt.Errorf("invalid key time %q, want id:time", pair)
+		}
+		times[strings.TrimSpace(id)] = strings.TrimSpace(at)
+	}
+	*k = times
+	return nil
+}
+
 func NewDatabases(cfgs DatabaseCfgs) Databases {
 	return Databases{
 		Pg: openPostgres(cfgs.Pg),
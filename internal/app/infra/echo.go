@@ -1,6 +1,8 @@
 package infra
 
 import (
+	"fmt"
+	"net"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -13,12 +15,22 @@ type (
 		ReadTimeout  time.Duration `envconfig:"READ_TIMEOUT" default:"5s"`
 		WriteTimeout time.Duration `envconfig:"WRITE_TIMEOUT" default:"10s"`
 		Debug        bool          `envconfig:"DEBUG" default:"true"`
+		// TrustedProxies lists the CIDR ranges of the reverse proxies in front
+		// of the app. X-Forwarded-For is only believed when the request came
+		// through one of them; when empty the peer address is used as is.
+		TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
 	}
 )
 
-func NewEcho(cfg *AppCfg) *echo.Echo {
+func NewEcho(cfg *AppCfg) (*echo.Echo, error) {
 	e := echo.New()
 
+	extractor, err := newIPExtractor(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	e.IPExtractor = extractor
+
 	e.Use(echoMiddleware.Recover())
 	e.Use(echoMiddleware.CORS())
 	e.Use(echoMiddleware.Gzip())
@@ -26,5 +38,28 @@ func NewEcho(cfg *AppCfg) *echo.Echo {
 
 	e.HideBanner = true
 	e.Debug = cfg.Debug
-	return e
+	return e, nil
+}
+
+// newIPExtractor decides where RealIP reads the client address from. Client
+// sent forwarding headers are ignored unless a trusted proxy added them, so
+// they cannot be used to pose as another address.
+func newIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("APP_TRUSTED_PROXIES: %w", err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
 }
\ No newline at end of file
@@ -33,3 +33,48 @@ func LoadJwtCfg() (*JwtCfg, error) {
 	}
 	return &cfg, nil
 }
+
+func LoadOrderCfg() (*OrderCfg, error) {
+	var cfg OrderCfg
+	prefix := "ORDER"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, err)
+	}
+	return &cfg, nil
+}
+
+func LoadPaymentCfg() (*PaymentCfg, error) {
+	var cfg PaymentCfg
+	prefix := "PAYMENT"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, err)
+	}
+	return &cfg, nil
+}
+
+func LoadIdempotencyCfg() (*IdempotencyCfg, error) {
+	var cfg IdempotencyCfg
+	prefix := "IDEMPOTENCY"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, err)
+	}
+	return &cfg, nil
+}
+
+func LoadMailCfg() (*MailCfg, error) {
+	var cfg MailCfg
+	prefix := "MAIL"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, err)
+	}
+	return &cfg, nil
+}
+
+func LoadAuthCfg() (*AuthCfg, error) {
+	var cfg AuthCfg
+	prefix := "AUTH"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, err)
+	}
+	return &cfg, nil
+}
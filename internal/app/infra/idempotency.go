@@ -0,0 +1,14 @@
+package infra
+
+import "time"
+
+type (
+	IdempotencyCfg struct {
+		Retention time.Duration `envconfig:"RETENTION" default:"24h"`
+		// InFlightTimeout is how long a key may stay reserved without a
+		// response before it is treated as abandoned, e.g. after a crash. It
+		// must comfortably exceed the slowest request it guards.
+		InFlightTimeout time.Duration `envconfig:"IN_FLIGHT_TIMEOUT" default:"5m"`
+		CleanupInterval time.Duration `envconfig:"CLEANUP_INTERVAL" default:"1h"`
+	}
+)
@@ -0,0 +1,30 @@
+package infra
+
+import "be-shop/pkg/mailer"
+
+type (
+	MailCfg struct {
+		// Driver is "smtp" or "log".
+		Driver   string `envconfig:"DRIVER" default:"log"`
+		Host     string `envconfig:"HOST" default:"localhost"`
+		Port     string `envconfig:"PORT" default:"587"`
+		Username string `envconfig:"USERNAME"`
+		Password string `envconfig:"PASSWORD"`
+		From     string `envconfig:"FROM" default:"be-shop <no-reply@be-shop.local>"`
+		// Dir is where the log driver also writes messages, if set.
+		Dir string `envconfig:"DIR"`
+	}
+)
+
+func NewMailer(cfg *MailCfg) mailer.Mailer {
+	if cfg.Driver == "smtp" {
+		return &mailer.SMTP{
+			Host:     cfg.Host,
+			Port:     cfg.Port,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			From:     cfg.From,
+		}
+	}
+	return &mailer.Log{From: cfg.From, Dir: cfg.Dir}
+}
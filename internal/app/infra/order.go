@@ -0,0 +1,11 @@
+package infra
+
+import "time"
+
+type (
+	OrderCfg struct {
+		ReservationWindow time.Duration `envconfig:"RESERVATION_WINDOW" default:"30m"`
+		ExpiryInterval    time.Duration `envconfig:"EXPIRY_INTERVAL" default:"1m"`
+		ExpiryBatchSize   int           `envconfig:"EXPIRY_BATCH_SIZE" default:"100"`
+	}
+)
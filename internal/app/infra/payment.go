@@ -0,0 +1,12 @@
+package infra
+
+// SampleSimulatorSecret is the placeholder shipped in .env.example. It is
+// public, so a simulator configured with it would accept forged webhooks.
+const SampleSimulatorSecret = "simulator-secret"
+
+type (
+	PaymentCfg struct {
+		Provider        string `envconfig:"PROVIDER" default:"simulator"`
+		SimulatorSecret string `envconfig:"SIMULATOR_SECRET"`
+	}
+)
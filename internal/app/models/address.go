@@ -0,0 +1,54 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+type (
+	// Address is an entry in a user's address book. Orders keep a copy of it
+	// as their shipping address, so editing or deleting an address never
+	// changes an order that was already placed.
+	Address struct {
+		ID            int    `json:"id,omitempty"`
+		UserID        int    `json:"user_id,omitempty"`
+		Label         string `json:"label,omitempty"`
+		RecipientName string `json:"recipient_name"`
+		Phone         string `json:"phone"`
+		Line1         string `json:"line1"`
+		Line2         string `json:"line2,omitempty"`
+		City          string `json:"city"`
+		Province      string `json:"province"`
+		PostalCode    string `json:"postal_code"`
+		Country       string `json:"country"`
+		IsDefault     bool   `json:"is_default"`
+		CreatedAt     string `json:"created_at,omitempty"`
+		UpdatedAt     string `json:"updated_at,omitempty"`
+	}
+)
+
+// Snapshot strips the address down to what is copied onto an order.
+func (a Address) Snapshot() Address {
+	a.UserID = 0
+	a.IsDefault = false
+	a.CreatedAt = ""
+	a.UpdatedAt = ""
+	return a
+}
+
+// Scan reads a JSONB shipping address snapshot.
+func (a *Address) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case []byte:
+		return json.Unmarshal(value, a)
+	case string:
+		return json.Unmarshal([]byte(value), a)
+	}
+	return fmt.Errorf("models: cannot scan %T into Address", src)
+}
+
+// Value writes the address as JSON for a JSONB column.
+func (a Address) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
@@ -1,14 +1,28 @@
 package models
 
+import "be-shop/pkg/money"
+
 type (
 	Cart struct {
-		ID           int     `json:"id,omitempty"`
-		UserID       int     `json:"user_id" validate:"required"`
-		ProductID    int     `json:"product_id" validate:"required"`
-		ProductName  string  `json:"product_name"`
-		ProductPrice float64 `json:"product_price,omitempty"`
-		Quantity     int     `json:"quantity" validate:"required"`
-		CreatedAt    string  `json:"created_at,omitempty"`
-		UpdatedAt    string  `json:"updated_at,omitempty"`
+		ID             int          `json:"id,omitempty"`
+		UserID         int          `json:"user_id" validate:"required"`
+		ProductID      int          `json:"product_id" validate:"required"`
+		ProductName    string       `json:"product_name"`
+		CategoryID     int          `json:"category_id,omitempty"`
+		ProductPrice   *money.Money `json:"product_price,omitempty"`
+		PriceAtAdd     *money.Money `json:"price_at_add,omitempty"`
+		Quantity       int          `json:"quantity" validate:"required"`
+		LineTotal      *money.Money `json:"line_total,omitempty"`
+		PriceChanged   bool         `json:"price_changed"`
+		ProductDeleted bool         `json:"product_deleted"`
+		CreatedAt      string       `json:"created_at,omitempty"`
+		UpdatedAt      string       `json:"updated_at,omitempty"`
+	}
+
+	// UnavailableCartItem is a cart line whose product has been deleted.
+	UnavailableCartItem struct {
+		CartID    int    `json:"cart_id"`
+		ProductID int    `json:"product_id"`
+		Name      string `json:"product_name"`
 	}
 )
@@ -1,10 +1,77 @@
 package models
 
 type (
+	// Category is a node in the category tree. A nil ParentID makes it a top
+	// level category; Children is only filled in when returning the tree.
 	Category struct {
-		ID        int    `json:"id,omitempty"`
-		Name      string `json:"name" validate:"required"`
-		CreatedAt string `json:"created_at,omitempty"`
-		UpdatedAt string `json:"updated_at,omitempty"`
+		ID        int        `json:"id,omitempty"`
+		ParentID  *int       `json:"parent_id" validate:"omitempty,gt=0"`
+		Name      string     `json:"name" validate:"required,max=255"`
+		Children  []Category `json:"children,omitempty"`
+		CreatedAt string     `json:"created_at,omitempty"`
+		UpdatedAt string     `json:"updated_at,omitempty"`
+	}
+
+	// CategoryCrumb is one step of a product's breadcrumb trail.
+	CategoryCrumb struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
 	}
 )
+
+// CategoryTree arranges a flat list of categories into trees under their
+// parents, keeping the order of the list among siblings.
+func CategoryTree(categories []Category) []Category {
+	children := make(map[int][]Category, len(categories))
+	for _, category := range categories {
+		parent := 0
+		if category.ParentID != nil {
+			parent = *category.ParentID
+		}
+		children[parent] = append(children[parent], category)
+	}
+
+	var build func(parent int) []Category
+	build = func(parent int) []Category {
+		nodes := children[parent]
+		for i := range nodes {
+			nodes[i].Children = build(nodes[i].ID)
+		}
+		return nodes
+	}
+
+	roots := build(0)
+	if roots == nil {
+		roots = make([]Category, 0)
+	}
+	return roots
+}
+
+// CategoryBreadcrumbs maps each category id to the trail of categories from
+// the top of the tree down to and including it.
+func CategoryBreadcrumbs(categories []Category) map[int][]CategoryCrumb {
+	byID := make(map[int]Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	trails := make(map[int][]CategoryCrumb, len(categories))
+	for _, category := range categories {
+		var trail []CategoryCrumb
+		node, ok := category, true
+		// the length bound guards against a cycle ever slipping into the data
+		for ok && len(trail) <= len(categories) {
+			trail = append(trail, CategoryCrumb{ID: node.ID, Name: node.Name})
+			if node.ParentID == nil {
+				break
+			}
+			node, ok = byID[*node.ParentID]
+		}
+
+		for i, j := 0, len(trail)-1; i < j; i, j = i+1, j-1 {
+			trail[i], trail[j] = trail[j], trail[i]
+		}
+		trails[category.ID] = trail
+	}
+	return trails
+}
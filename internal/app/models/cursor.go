@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("cursor is invalid")
+
+type (
+	// Cursor is a position in a keyset-paginated listing: the sort key and id
+	// of the row the next page starts after (or, when Backward, before).
+	// Clients only ever see it in the opaque form returned by Encode.
+	Cursor struct {
+		Sort     string `json:"s,omitempty"`
+		Key      string `json:"k"`
+		ID       int64  `json:"i"`
+		Backward bool   `json:"b,omitempty"`
+	}
+
+	// CursorPage holds the cursors either side of a keyset page. Each is
+	// empty when there is nothing further in that direction.
+	CursorPage struct {
+		Next string
+		Prev string
+	}
+)
+
+// IsStart reports whether c points at the start of the listing rather than
+// at a row.
+func (c Cursor) IsStart() bool {
+	return c.ID == 0
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// cursorTimeLayouts are the forms a timestamp key takes: RFC 3339 as the
+// driver formats a scanned time, and Postgres' own text output for keys read
+// back as ::text.
+var cursorTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999"}
+
+var cursorDecimal = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// DecodeCursor reads a cursor produced by Encode. An empty string is the
+// start of the listing. The key must be of the type its sort is keyed on, so
+// a tampered cursor is refused here rather than failing in the database.
+func DecodeCursor(s string) (c Cursor, err error) {
+	if s == "" {
+		return
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err = json.Unmarshal(data, &c); err != nil || c.ID <= 0 || !c.validKey() {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return
+}
+
+// validKey reports whether Key parses as the type of c.Sort's key. Order
+// cursors carry no sort and are keyed on the creation time.
+func (c Cursor) validKey() bool {
+	switch c.Sort {
+	case "", ProductSortNewest:
+		for _, layout := range cursorTimeLayouts {
+			if _, err := time.Parse(layout, c.Key); err == nil {
+				return true
+			}
+		}
+		return false
+	case ProductSortPriceAsc, ProductSortPriceDesc:
+		return cursorDecimal.MatchString(c.Key)
+	case ProductSortNameAsc, ProductSortNameDesc:
+		return true
+	}
+	return false
+}
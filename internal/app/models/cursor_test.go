@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	raw := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    Cursor
+		wantErr bool
+	}{
+		{name: "empty is the start", in: "", want: Cursor{}},
+		{name: "order cursor", in: Cursor{Key: "2024-05-01T10:00:00.123456Z", ID: 7}.Encode(), want: Cursor{Key: "2024-05-01T10:00:00.123456Z", ID: 7}},
+		{name: "backward", in: Cursor{Key: "2024-05-01T10:00:00Z", ID: 7, Backward: true}.Encode(), want: Cursor{Key: "2024-05-01T10:00:00Z", ID: 7, Backward: true}},
+		{name: "newest with postgres time", in: Cursor{Sort: ProductSortNewest, Key: "2024-05-01 10:00:00.5", ID: 3}.Encode(), want: Cursor{Sort: ProductSortNewest, Key: "2024-05-01 10:00:00.5", ID: 3}},
+		{name: "price", in: Cursor{Sort: ProductSortPriceAsc, Key: "19.99", ID: 3}.Encode(), want: Cursor{Sort: ProductSortPriceAsc, Key: "19.99", ID: 3}},
+		{name: "name", in: Cursor{Sort: ProductSortNameDesc, Key: "o'brien's <mug>", ID: 3}.Encode(), want: Cursor{Sort: ProductSortNameDesc, Key: "o'brien's <mug>", ID: 3}},
+		{name: "bad base64", in: "not base64!", wantErr: true},
+		{name: "padded base64", in: base64.URLEncoding.EncodeToString([]byte(`{"k":"2024-05-01T10:00:00Z","i":1}`)), wantErr: true},
+		{name: "bad json", in: raw(`{"k":`), wantErr: true},
+		{name: "zero id", in: raw(`{"k":"2024-05-01T10:00:00Z","i":0}`), wantErr: true},
+		{name: "negative id", in: raw(`{"k":"2024-05-01T10:00:00Z","i":-4}`), wantErr: true},
+		{name: "garbage time", in: Cursor{Key: "yesterday", ID: 7}.Encode(), wantErr: true},
+		{name: "garbage newest", in: Cursor{Sort: ProductSortNewest, Key: "'; DROP TABLE products", ID: 7}.Encode(), wantErr: true},
+		{name: "garbage price", in: Cursor{Sort: ProductSortPriceDesc, Key: "cheap", ID: 7}.Encode(), wantErr: true},
+		{name: "exponent price", in: Cursor{Sort: ProductSortPriceDesc, Key: "1e5", ID: 7}.Encode(), wantErr: true},
+		{name: "unknown sort", in: Cursor{Sort: "popular", Key: "1", ID: 7}.Encode(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := DecodeCursor(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("%s: DecodeCursor error = %v, want ErrInvalidCursor", tt.name, err)
+			}
+			if got != (Cursor{}) {
+				t.Errorf("%s: DecodeCursor = %+v on error, want zero cursor", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: DecodeCursor = %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+type (
+	IdempotencyKey struct {
+		ID           int
+		UserID       int
+		Key          string
+		Endpoint     string
+		RequestHash  string
+		ResponseCode *int
+		ResponseBody []byte
+	}
+)
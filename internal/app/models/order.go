@@ -1,13 +1,83 @@
 package models
 
+import (
+	"be-shop/pkg/money"
+	"time"
+)
+
+const (
+	OrderStatusPending       OrderStatus = "Pending"
+	OrderStatusPaid          OrderStatus = "Paid"
+	OrderStatusProcessing    OrderStatus = "Processing"
+	OrderStatusShipped       OrderStatus = "Shipped"
+	OrderStatusDelivered     OrderStatus = "Delivered"
+	OrderStatusCancelled     OrderStatus = "Cancelled"
+	OrderStatusExpired       OrderStatus = "Expired"
+	OrderStatusRefundPending OrderStatus = "RefundPending"
+	OrderStatusRefunded      OrderStatus = "Refunded"
+)
+
 type (
+	OrderStatus string
+
 	Order struct {
-		ID          int     `json:"id,omitempty"`
-		UserID      int     `json:"user_id" validate:"required"`
-		TotalAmount float64 `json:"total_amount" validate:"required"`
-		OrderCode   string  `json:"order_code" validate:"required"`
-		Status      string  `json:"status"`
-		CreatedAt   string  `json:"created_at,omitempty"`
-		UpdatedAt   string  `json:"updated_at,omitempty"`
+		ID               int                  `json:"id,omitempty"`
+		UserID           int                  `json:"user_id" validate:"required"`
+		SubtotalAmount   money.Money          `json:"subtotal_amount"`
+		DiscountAmount   money.Money          `json:"discount_amount"`
+		TotalAmount      money.Money          `json:"total_amount" validate:"required"`
+		PromotionID      *int                 `json:"promotion_id,omitempty"`
+		OrderCode        string               `json:"order_code" validate:"required"`
+		Status           OrderStatus          `json:"status"`
+		ExpiresAt        *time.Time           `json:"expires_at,omitempty"`
+		PaymentProvider  string               `json:"payment_provider,omitempty"`
+		PaymentReference string               `json:"payment_reference,omitempty"`
+		ShippingAddress  *Address             `json:"shipping_address,omitempty"`
+		Items            []OrderItem          `json:"items,omitempty"`
+		History          []OrderStatusHistory `json:"history,omitempty"`
+		CreatedAt        string               `json:"created_at,omitempty"`
+		UpdatedAt        string               `json:"updated_at,omitempty"`
+	}
+
+	OrderItem struct {
+		ID          int         `json:"id,omitempty"`
+		OrderID     int         `json:"order_id"`
+		ProductID   int         `json:"product_id"`
+		ProductName string      `json:"product_name"`
+		Quantity    int         `json:"quantity"`
+		Price       money.Money `json:"price"`
+		CreatedAt   string      `json:"created_at,omitempty"`
+		UpdatedAt   string      `json:"updated_at,omitempty"`
+	}
+
+	// OrderTransition describes a single status change. ChangedBy is nil when
+	// the change is made by the system rather than a user.
+	OrderTransition struct {
+		OrderID   int64
+		From      OrderStatus
+		To        OrderStatus
+		ChangedBy *int
+		Note      string
+	}
+
+	OrderStatusHistory struct {
+		ID         int         `json:"id"`
+		OrderID    int         `json:"order_id"`
+		FromStatus OrderStatus `json:"from_status"`
+		ToStatus   OrderStatus `json:"to_status"`
+		ChangedBy  *int        `json:"changed_by"`
+		Note       string      `json:"note,omitempty"`
+		CreatedAt  string      `json:"created_at"`
+	}
+
+	// OrderFilter narrows an order listing. A zero UserID lists every user's
+	// orders.
+	OrderFilter struct {
+		UserID int64
+		Status OrderStatus
+		From   *time.Time
+		To     *time.Time
+		Limit  int
+		Offset int
 	}
 )
@@ -1,12 +1,69 @@
 package models
 
+import (
+	"be-shop/pkg/money"
+	"strconv"
+)
+
+const (
+	ProductSortNewest    = "newest"
+	ProductSortPriceAsc  = "price_asc"
+	ProductSortPriceDesc = "price_desc"
+	ProductSortNameAsc   = "name_asc"
+	ProductSortNameDesc  = "name_desc"
+)
+
+const (
+	ProductMatchFullText = "fulltext"
+	ProductMatchFuzzy    = "fuzzy"
+)
+
 type (
 	Product struct {
-		ID         int     `json:"id,omitempty"`
-		Name       string  `json:"name" validate:"required"`
-		CategoryID string  `json:"category_id" validate:"required"`
-		Price      float64 `json:"price" validate:"required,gt=0"`
-		CreatedAt  string  `json:"created_at,omitempty"`
-		UpdatedAt  string  `json:"updated_at,omitempty"`
+		ID          int         `json:"id,omitempty"`
+		Name        string      `json:"name" validate:"required"`
+		Description string      `json:"description" validate:"max=5000"`
+		CategoryID  string      `json:"category_id" validate:"required"`
+		Price       money.Money `json:"price" validate:"required,gt=0"`
+		Stock       int         `json:"stock" validate:"gte=0"`
+		CreatedAt   string      `json:"created_at,omitempty"`
+		UpdatedAt   string      `json:"updated_at,omitempty"`
+		DeletedAt   *string     `json:"deleted_at,omitempty"`
+		// Breadcrumbs is the product's category trail from the top of the
+		// category tree down; it is never read from requests.
+		Breadcrumbs []CategoryCrumb `json:"breadcrumbs,omitempty" validate:"-"`
+	}
+
+	// ProductSearchResult is a product matched by search. Snippet holds the
+	// matching text as escaped HTML with the hits wrapped in <mark> tags;
+	// Match is ProductMatchFullText or, when only a misspelled form matched,
+	// ProductMatchFuzzy.
+	ProductSearchResult struct {
+		Product
+		Rank    float64 `json:"rank"`
+		Snippet string  `json:"snippet"`
+		Match   string  `json:"match"`
+	}
+
+	// ProductFilter narrows a product listing. Zero values leave that part
+	// of the listing unfiltered; an empty Sort lists the newest first.
+	// Deleted products are hidden unless Deleted is set, which lists only
+	// them.
+	ProductFilter struct {
+		Deleted    bool
+		Search     string
+		CategoryID int64
+		MinPrice   *money.Money
+		MaxPrice   *money.Money
+		Sort       string
+		Limit      int
+		Offset     int
 	}
 )
+
+// SetBreadcrumbs fills in Breadcrumbs from trails, as built by
+// CategoryBreadcrumbs.
+func (p *Product) SetBreadcrumbs(trails map[int][]CategoryCrumb) {
+	id, _ := strconv.Atoi(p.CategoryID)
+	p.Breadcrumbs = trails[id]
+}
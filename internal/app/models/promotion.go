@@ -0,0 +1,176 @@
+package models
+
+import (
+	"be-shop/pkg/money"
+	"errors"
+	"time"
+)
+
+const (
+	PromotionTypePercentage PromotionType = "percentage"
+	PromotionTypeFixed      PromotionType = "fixed"
+)
+
+var (
+	ErrPromotionInactive      = errors.New("promotion is not active")
+	ErrPromotionNotStarted    = errors.New("promotion has not started yet")
+	ErrPromotionEnded         = errors.New("promotion has ended")
+	ErrPromotionUsageLimit    = errors.New("promotion has reached its usage limit")
+	ErrPromotionUserLimit     = errors.New("promotion has already been used the maximum number of times")
+	ErrPromotionNotApplicable = errors.New("promotion does not apply to any item in the cart")
+	ErrPromotionMinSpend      = errors.New("cart does not reach the promotion's minimum spend")
+)
+
+type (
+	PromotionType string
+
+	// Promotion is a coupon code. A promotion without product or category
+	// scopes applies to the whole cart; otherwise only matching lines count
+	// towards the minimum spend and the discount.
+	Promotion struct {
+		ID           int           `json:"id"`
+		Code         string        `json:"code"`
+		Description  string        `json:"description,omitempty"`
+		Type         PromotionType `json:"type"`
+		Percent      int           `json:"percent,omitempty"`
+		Amount       money.Money   `json:"amount"`
+		MaxDiscount  *money.Money  `json:"max_discount,omitempty"`
+		MinSpend     money.Money   `json:"min_spend"`
+		UsageLimit   *int          `json:"usage_limit,omitempty"`
+		PerUserLimit *int          `json:"per_user_limit,omitempty"`
+		UsedCount    int           `json:"used_count"`
+		StartsAt     time.Time     `json:"starts_at"`
+		EndsAt       *time.Time    `json:"ends_at,omitempty"`
+		Active       bool          `json:"active"`
+		ProductIDs   []int64       `json:"product_ids"`
+		CategoryIDs  []int64       `json:"category_ids"`
+		CreatedAt    string        `json:"created_at,omitempty"`
+		UpdatedAt    string        `json:"updated_at,omitempty"`
+		// CategorySubtreeIDs holds CategoryIDs and every category below
+		// them, filled in when the promotion is loaded.
+		CategorySubtreeIDs []int64 `json:"-"`
+	}
+
+	// AppliedCoupon is the coupon attached to a cart and what it is worth
+	// right now. Error explains why it currently gives no discount.
+	AppliedCoupon struct {
+		Code     string      `json:"code"`
+		Discount money.Money `json:"discount"`
+		Error    string      `json:"error,omitempty"`
+	}
+
+	// CartSummary totals only the lines that can still be bought; lines for
+	// deleted products are listed but not counted.
+	CartSummary struct {
+		Items     []Cart         `json:"items"`
+		ItemCount int            `json:"item_count"`
+		Subtotal  money.Money    `json:"subtotal"`
+		Discount  money.Money    `json:"discount"`
+		Total     money.Money    `json:"total"`
+		Coupon    *AppliedCoupon `json:"coupon,omitempty"`
+	}
+)
+
+// Applies reports whether a cart line is within the promotion's scope. A
+// category scope covers the category's subcategories too.
+func (p Promotion) Applies(line Cart) bool {
+	if len(p.ProductIDs) == 0 && len(p.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range p.ProductIDs {
+		if id == int64(line.ProductID) {
+			return true
+		}
+	}
+	for _, ids := range [][]int64{p.CategoryIDs, p.CategorySubtreeIDs} {
+		for _, id := range ids {
+			if id == int64(line.CategoryID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckAvailability verifies the promotion can be redeemed at now by a user
+// who has already redeemed it userRedemptions times.
+func (p Promotion) CheckAvailability(now time.Time, userRedemptions int) error {
+	switch {
+	case !p.Active:
+		return ErrPromotionInactive
+	case now.Before(p.StartsAt):
+		return ErrPromotionNotStarted
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return ErrPromotionEnded
+	case p.UsageLimit != nil && p.UsedCount >= *p.UsageLimit:
+		return ErrPromotionUsageLimit
+	case p.PerUserLimit != nil && userRedemptions >= *p.PerUserLimit:
+		return ErrPromotionUserLimit
+	}
+	return nil
+}
+
+// Discount works out what the promotion takes off the given cart lines. Each
+// line must carry its ProductPrice. Percentage discounts are rounded half up
+// to the minor unit and capped by MaxDiscount; no discount ever exceeds the
+// eligible subtotal.
+func (p Promotion) Discount(lines []Cart) (discount money.Money, err error) {
+	var eligible money.Money
+	matched := false
+	for _, line := range lines {
+		if line.ProductPrice == nil || !p.Applies(line) {
+			continue
+		}
+		matched = true
+		var lineTotal money.Money
+		lineTotal, err = line.ProductPrice.Mul(int64(line.Quantity))
+		if err != nil {
+			return
+		}
+		eligible, err = eligible.Add(lineTotal)
+		if err != nil {
+			return
+		}
+	}
+	// a coupon priced in another currency cannot be compared with the cart
+	if !matched || eligible.Currency != p.MinSpend.Currency {
+		err = ErrPromotionNotApplicable
+		return
+	}
+
+	cmp, err := eligible.Cmp(p.MinSpend)
+	if err != nil {
+		return
+	}
+	if cmp < 0 {
+		err = ErrPromotionMinSpend
+		return
+	}
+
+	switch p.Type {
+	case PromotionTypePercentage:
+		discount, err = eligible.Percent(int64(p.Percent))
+		if err != nil {
+			return
+		}
+		if p.MaxDiscount != nil {
+			if cmp, err = discount.Cmp(*p.MaxDiscount); err != nil {
+				return
+			}
+			if cmp > 0 {
+				discount = *p.MaxDiscount
+			}
+		}
+	default:
+		discount = p.Amount
+	}
+
+	if cmp, err = discount.Cmp(eligible); err != nil {
+		return
+	}
+	if cmp > 0 {
+		discount = eligible
+	}
+	discount.Currency = eligible.Currency
+	return
+}
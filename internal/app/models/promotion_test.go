@@ -0,0 +1,191 @@
+package models
+
+import (
+	"be-shop/pkg/money"
+	"errors"
+	"testing"
+	"time"
+)
+
+func idr(amount int64) money.Money {
+	return money.New(amount, "IDR")
+}
+
+func cartLine(productID, categoryID int, price money.Money, quantity int) Cart {
+	return Cart{ProductID: productID, CategoryID: categoryID, ProductPrice: &price, Quantity: quantity}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestPromotionApplies(t *testing.T) {
+	line := cartLine(10, 3, idr(100), 1)
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		want      bool
+	}{
+		{name: "no scope", promotion: Promotion{}, want: true},
+		{name: "product scope", promotion: Promotion{ProductIDs: []int64{9, 10}}, want: true},
+		{name: "category scope", promotion: Promotion{CategoryIDs: []int64{3}}, want: true},
+		{name: "other product", promotion: Promotion{ProductIDs: []int64{11}}, want: false},
+		{name: "other category", promotion: Promotion{CategoryIDs: []int64{4}}, want: false},
+		{name: "either scope", promotion: Promotion{ProductIDs: []int64{11}, CategoryIDs: []int64{3}}, want: true},
+		{name: "parent category scope", promotion: Promotion{CategoryIDs: []int64{1}, CategorySubtreeIDs: []int64{1, 2, 3}}, want: true},
+		{name: "sibling category scope", promotion: Promotion{CategoryIDs: []int64{2}, CategorySubtreeIDs: []int64{2, 5}}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.promotion.Applies(line); got != tt.want {
+			t.Errorf("%s: Applies = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPromotionCheckAvailability(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	ends := now.Add(time.Hour)
+	endedAtNow := now
+	base := Promotion{Active: true, StartsAt: now.Add(-time.Hour), EndsAt: &ends}
+
+	tests := []struct {
+		name        string
+		change      func(p *Promotion)
+		redemptions int
+		wantErr     error
+	}{
+		{name: "available"},
+		{name: "open ended", change: func(p *Promotion) { p.EndsAt = nil }},
+		{name: "starts now", change: func(p *Promotion) { p.StartsAt = now }},
+		{name: "inactive", change: func(p *Promotion) { p.Active = false }, wantErr: ErrPromotionInactive},
+		{name: "not started", change: func(p *Promotion) { p.StartsAt = now.Add(time.Second) }, wantErr: ErrPromotionNotStarted},
+		{name: "ends now", change: func(p *Promotion) { p.EndsAt = &endedAtNow }, wantErr: ErrPromotionEnded},
+		{name: "below usage limit", change: func(p *Promotion) { p.UsageLimit, p.UsedCount = intPtr(5), 4 }},
+		{name: "usage limit reached", change: func(p *Promotion) { p.UsageLimit, p.UsedCount = intPtr(5), 5 }, wantErr: ErrPromotionUsageLimit},
+		{name: "below user limit", change: func(p *Promotion) { p.PerUserLimit = intPtr(2) }, redemptions: 1},
+		{name: "user limit reached", change: func(p *Promotion) { p.PerUserLimit = intPtr(2) }, redemptions: 2, wantErr: ErrPromotionUserLimit},
+	}
+
+	for _, tt := range tests {
+		promotion := base
+		if tt.change != nil {
+			tt.change(&promotion)
+		}
+		if err := promotion.CheckAvailability(now, tt.redemptions); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: CheckAvailability = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPromotionDiscount(t *testing.T) {
+	maxDiscount := idr(500)
+	usd := money.New(1000, "USD")
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		lines     []Cart
+		want      money.Money
+		wantErr   error
+	}{
+		{
+			name:      "percentage rounds half up",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: 10, MinSpend: idr(0)},
+			lines:     []Cart{cartLine(1, 1, idr(1995), 1)},
+			want:      idr(200),
+		},
+		{
+			name:      "percentage rounds down below half",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: 10, MinSpend: idr(0)},
+			lines:     []Cart{cartLine(1, 1, idr(1994), 1)},
+			want:      idr(199),
+		},
+		{
+			name:      "percentage capped",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: 50, MaxDiscount: &maxDiscount, MinSpend: idr(0)},
+			lines:     []Cart{cartLine(1, 1, idr(2000), 3)},
+			want:      idr(500),
+		},
+		{
+			name:      "percentage under the cap",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: 10, MaxDiscount: &maxDiscount, MinSpend: idr(0)},
+			lines:     []Cart{cartLine(1, 1, idr(2000), 2)},
+			want:      idr(400),
+		},
+		{
+			name:      "fixed",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: idr(300), MinSpend: idr(0)},
+			lines:     []Cart{cartLine(1, 1, idr(1000), 1)},
+			want:      idr(300),
+		},
+		{
+			name:      "fixed never exceeds the eligible subtotal",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: idr(5000), MinSpend: idr(0)},
+			lines:     []Cart{cartLine(1, 1, idr(1000), 2)},
+			want:      idr(2000),
+		},
+		{
+			name:      "minimum spend met exactly",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: idr(100), MinSpend: idr(2000)},
+			lines:     []Cart{cartLine(1, 1, idr(1000), 2)},
+			want:      idr(100),
+		},
+		{
+			name:      "minimum spend missed",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: idr(100), MinSpend: idr(2001)},
+			lines:     []Cart{cartLine(1, 1, idr(1000), 2)},
+			wantErr:   ErrPromotionMinSpend,
+		},
+		{
+			name:      "only scoped lines count",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: 10, MinSpend: idr(1500), ProductIDs: []int64{2}},
+			lines:     []Cart{cartLine(1, 1, idr(10000), 1), cartLine(2, 1, idr(1000), 2)},
+			want:      idr(200),
+		},
+		{
+			name:      "scoped lines below minimum spend",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: idr(100), MinSpend: idr(5000), CategoryIDs: []int64{2}},
+			lines:     []Cart{cartLine(1, 1, idr(10000), 1), cartLine(2, 2, idr(1000), 1)},
+			wantErr:   ErrPromotionMinSpend,
+		},
+		{
+			name:      "nothing in scope",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: idr(100), MinSpend: idr(0), ProductIDs: []int64{9}},
+			lines:     []Cart{cartLine(1, 1, idr(1000), 1)},
+			wantErr:   ErrPromotionNotApplicable,
+		},
+		{
+			name:      "lines without a price are skipped",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: idr(100), MinSpend: idr(0)},
+			lines:     []Cart{{ProductID: 1, Quantity: 1}},
+			wantErr:   ErrPromotionNotApplicable,
+		},
+		{
+			name:      "coupon in another currency",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: usd, MinSpend: money.New(0, "USD")},
+			lines:     []Cart{cartLine(1, 1, idr(1000), 1)},
+			wantErr:   ErrPromotionNotApplicable,
+		},
+		{
+			name:      "cart in mixed currencies",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: idr(100), MinSpend: idr(0)},
+			lines:     []Cart{cartLine(1, 1, idr(1000), 1), cartLine(2, 1, usd, 1)},
+			wantErr:   money.ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.promotion.Discount(tt.lines)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: Discount error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Discount = %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
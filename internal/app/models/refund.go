@@ -0,0 +1,22 @@
+package models
+
+import "be-shop/pkg/money"
+
+const (
+	RefundStatusPending   = "Pending"
+	RefundStatusCompleted = "Completed"
+	RefundStatusFailed    = "Failed"
+)
+
+type (
+	Refund struct {
+		ID                int         `json:"id,omitempty"`
+		OrderID           int         `json:"order_id"`
+		Amount            money.Money `json:"amount"`
+		Status            string      `json:"status"`
+		Reason            string      `json:"reason,omitempty"`
+		ProviderReference string      `json:"provider_reference,omitempty"`
+		CreatedAt         string      `json:"created_at,omitempty"`
+		UpdatedAt         string      `json:"updated_at,omitempty"`
+	}
+)
@@ -1,10 +1,16 @@
 package models
 
 type (
+	// PaginationRequest selects a page either by number or, when UseCursor is
+	// set, by keyset cursor. UseCursor is set by the controller whenever the
+	// cursor parameter is present, even empty, so "?cursor=" asks for the
+	// first page in cursor mode.
 	PaginationRequest struct {
-		Page   int    `query:"page"`
-		Limit  int    `query:"limit"`
-		Search string `query:"search"`
+		Page      int    `query:"page"`
+		Limit     int    `query:"limit"`
+		Search    string `query:"search"`
+		Cursor    string `query:"cursor"`
+		UseCursor bool
 	}
 )
 
@@ -1,5 +1,7 @@
 package models
 
+import "math"
+
 type (
 	DefaultResponse struct {
 		Code    int    `json:"code"`
@@ -8,13 +10,18 @@ type (
 		Error   any    `json:"error,omitempty"`
 	}
 
+	// DefaultMetaData describes a page of results. Page-number listings fill
+	// in the page and totals; cursor listings leave those zero and return
+	// NextCursor and PrevCursor instead.
 	DefaultMetaData struct {
-		Page        uint `json:"page"`
-		TotalPages  uint `json:"totalPages"`
-		TotalItems  uint `json:"totalItems"`
-		Limit       uint `json:"limit"`
-		HasNext     bool `json:"hasNext"`
-		HasPrevious bool `json:"hasPrevious"`
+		Page        uint   `json:"page"`
+		TotalPages  uint   `json:"totalPages"`
+		TotalItems  uint   `json:"totalItems"`
+		Limit       uint   `json:"limit"`
+		HasNext     bool   `json:"hasNext"`
+		HasPrevious bool   `json:"hasPrevious"`
+		NextCursor  string `json:"next_cursor,omitempty"`
+		PrevCursor  string `json:"prev_cursor,omitempty"`
 	}
 
 	DefaultPaginationResponseData struct {
@@ -22,3 +29,32 @@ type (
 		DefaultMetaData `json:"meta"`
 	}
 )
+
+// NewPageMetaData describes page (counting from 1) of a page-number listing
+// with totalItems results in all.
+func NewPageMetaData(page, limit, totalItems int) DefaultMetaData {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = int(math.Ceil(float64(totalItems) / float64(limit)))
+	}
+
+	return DefaultMetaData{
+		Page:        uint(page),
+		TotalPages:  uint(totalPages),
+		TotalItems:  uint(totalItems),
+		Limit:       uint(limit),
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+}
+
+// NewCursorMetaData describes a page of a cursor listing.
+func NewCursorMetaData(limit int, page CursorPage) DefaultMetaData {
+	return DefaultMetaData{
+		Limit:       uint(limit),
+		HasNext:     page.Next != "",
+		HasPrevious: page.Prev != "",
+		NextCursor:  page.Next,
+		PrevCursor:  page.Prev,
+	}
+}
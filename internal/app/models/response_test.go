@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestNewPageMetaData(t *testing.T) {
+	tests := []struct {
+		page, limit, total int
+		want               DefaultMetaData
+	}{
+		{page: 1, limit: 10, total: 0, want: DefaultMetaData{Page: 1, Limit: 10}},
+		{page: 1, limit: 10, total: 10, want: DefaultMetaData{Page: 1, TotalPages: 1, TotalItems: 10, Limit: 10}},
+		{page: 1, limit: 10, total: 11, want: DefaultMetaData{Page: 1, TotalPages: 2, TotalItems: 11, Limit: 10, HasNext: true}},
+		{page: 2, limit: 10, total: 11, want: DefaultMetaData{Page: 2, TotalPages: 2, TotalItems: 11, Limit: 10, HasPrevious: true}},
+		{page: 2, limit: 10, total: 30, want: DefaultMetaData{Page: 2, TotalPages: 3, TotalItems: 30, Limit: 10, HasNext: true, HasPrevious: true}},
+		{page: 5, limit: 10, total: 30, want: DefaultMetaData{Page: 5, TotalPages: 3, TotalItems: 30, Limit: 10, HasPrevious: true}},
+		{page: 1, limit: 0, total: 30, want: DefaultMetaData{Page: 1, TotalItems: 30}},
+	}
+
+	for _, tt := range tests {
+		if got := NewPageMetaData(tt.page, tt.limit, tt.total); got != tt.want {
+			t.Errorf("NewPageMetaData(%d, %d, %d) = %+v, want %+v", tt.page, tt.limit, tt.total, got, tt.want)
+		}
+	}
+}
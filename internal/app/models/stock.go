@@ -0,0 +1,20 @@
+package models
+
+type (
+	StockAdjustment struct {
+		ID         int    `json:"id,omitempty"`
+		ProductID  int    `json:"product_id"`
+		Delta      int    `json:"delta"`
+		StockAfter int    `json:"stock_after"`
+		Reason     string `json:"reason"`
+		CreatedAt  string `json:"created_at,omitempty"`
+	}
+
+	InsufficientStockItem struct {
+		CartID    int    `json:"cart_id"`
+		ProductID int    `json:"product_id"`
+		Name      string `json:"product_name"`
+		Requested int    `json:"requested"`
+		Available int    `json:"available"`
+	}
+)
@@ -1,10 +1,64 @@
 package models
 
+import "time"
+
+const (
+	RoleCustomer Role = "customer"
+	RoleStaff    Role = "staff"
+	RoleAdmin    Role = "admin"
+
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposeEmailChange       TokenPurpose = "email_change"
+)
+
 type (
+	Role string
+
+	TokenPurpose string
+
 	User struct {
 		ID       int    `json:"id,omitempty"`
 		Email    string `json:"email" validate:"required,email"`
-		Username string `json:"username" validate:"required"`
+		Username string `json:"username" validate:"required,excludes=@"`
 		Password string `json:"password" validate:"required,min=8,max=20,uppercase,lowercase,number,specialchar"`
+		Role     Role   `json:"role,omitempty"`
+
+		EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	}
+
+	// RefreshToken is a stored refresh token. Only the SHA-256 of the token is
+	// kept; every token issued by rotating another shares its FamilyID.
+	RefreshToken struct {
+		ID         int
+		UserID     int
+		TokenHash  string
+		FamilyID   string
+		ParentID   *int
+		ReplacedBy *int
+		ExpiresAt  time.Time
+		RevokedAt  *time.Time
+	}
+
+	// UserToken is a single-use token mailed to a user, such as a password
+	// reset link. Only the SHA-256 of the token is stored.
+	UserToken struct {
+		ID        int
+		UserID    int
+		Purpose   TokenPurpose
+		TokenHash string
+		// NewEmail is the address an email_change token switches to.
+		NewEmail  *string
+		ExpiresAt time.Time
+		UsedAt    *time.Time
+	}
+
+	// Profile is what a signed in user sees about their own account.
+	Profile struct {
+		ID            int    `json:"id"`
+		Email         string `json:"email"`
+		Username      string `json:"username"`
+		Role          Role   `json:"role"`
+		EmailVerified bool   `json:"email_verified"`
 	}
 )
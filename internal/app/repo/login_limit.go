@@ -0,0 +1,47 @@
+package repo
+
+import "time"
+
+// LoginLimit is the throttling policy a LoginAttemptStore enforces on a key.
+type LoginLimit struct {
+	// MaxFailures failures lock the key for Lockout after the last of them.
+	// Zero disables the lockout.
+	MaxFailures int
+	Lockout     time.Duration
+	// From DelayAfter failures on, the next attempt must wait Delay after
+	// the last failure, doubling for every further failure up to MaxDelay.
+	// A zero Delay disables the delay.
+	DelayAfter int
+	Delay      time.Duration
+	MaxDelay   time.Duration
+}
+
+// RetryAfter returns how long after now a key with the given attempts must
+// wait before its next attempt, and whether that is because it is locked out
+// rather than merely delayed. A zero wait means the attempt may go ahead.
+func (l LoginLimit) RetryAfter(attempts LoginAttempts, now time.Time) (wait time.Duration, locked bool) {
+	if l.MaxFailures > 0 && attempts.Failures >= l.MaxFailures {
+		if wait = attempts.LastFailure.Add(l.Lockout).Sub(now); wait > 0 {
+			return wait, true
+		}
+	}
+	if l.Delay > 0 && attempts.Failures >= l.DelayAfter {
+		if wait = attempts.LastFailure.Add(l.DelayFor(attempts.Failures)).Sub(now); wait > 0 {
+			return wait, false
+		}
+	}
+	return 0, false
+}
+
+// DelayFor is the wait after the given number of failures: Delay at
+// DelayAfter failures, doubled for every failure past it, capped at MaxDelay.
+func (l LoginLimit) DelayFor(failures int) time.Duration {
+	delay := l.Delay
+	for i := l.DelayAfter; i < failures && delay < l.MaxDelay; i++ {
+		delay *= 2
+	}
+	if l.MaxDelay > 0 && delay > l.MaxDelay {
+		delay = l.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginLimitDelayFor(t *testing.T) {
+	limit := LoginLimit{DelayAfter: 3, Delay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 3, want: time.Second},
+		{failures: 4, want: 2 * time.Second},
+		{failures: 5, want: 4 * time.Second},
+		{failures: 6, want: 8 * time.Second},
+		{failures: 7, want: 10 * time.Second},
+		{failures: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := limit.DelayFor(tt.failures); got != tt.want {
+			t.Errorf("DelayFor(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginLimitRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limit := LoginLimit{
+		MaxFailures: 5,
+		Lockout:     15 * time.Minute,
+		DelayAfter:  3,
+		Delay:       time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+
+	tests := []struct {
+		name       string
+		limit      LoginLimit
+		attempts   LoginAttempts
+		wantWait   time.Duration
+		wantLocked bool
+	}{
+		{name: "no failures", limit: limit},
+		{name: "below delay", limit: limit, attempts: LoginAttempts{Failures: 2, LastFailure: now}},
+		{name: "first delay", limit: limit, attempts: LoginAttempts{Failures: 3, LastFailure: now}, wantWait: time.Second},
+		{name: "doubled delay", limit: limit, attempts: LoginAttempts{Failures: 4, LastFailure: now.Add(-time.Second)}, wantWait: time.Second},
+		{name: "delay over", limit: limit, attempts: LoginAttempts{Failures: 4, LastFailure: now.Add(-2 * time.Second)}},
+		{name: "locked", limit: limit, attempts: LoginAttempts{Failures: 5, LastFailure: now.Add(-time.Minute)}, wantWait: 14 * time.Minute, wantLocked: true},
+		{name: "lockout over", limit: limit, attempts: LoginAttempts{Failures: 5, LastFailure: now.Add(-15 * time.Minute)}},
+		{name: "lockout only", limit: LoginLimit{MaxFailures: 2, Lockout: time.Minute}, attempts: LoginAttempts{Failures: 1, LastFailure: now}},
+		{name: "lockout disabled", limit: LoginLimit{Lockout: time.Minute}, attempts: LoginAttempts{Failures: 100, LastFailure: now}},
+	}
+
+	for _, tt := range tests {
+		wait, locked := tt.limit.RetryAfter(tt.attempts, now)
+		if wait != tt.wantWait || locked != tt.wantLocked {
+			t.Errorf("%s: RetryAfter = %s, %v, want %s, %v", tt.name, wait, locked, tt.wantWait, tt.wantLocked)
+		}
+	}
+}
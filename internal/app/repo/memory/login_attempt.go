@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"be-shop/internal/app/repo"
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	loginAttemptStore struct {
+		mu       sync.Mutex
+		attempts map[string]repo.LoginAttempts
+		now      func() time.Time
+	}
+)
+
+func NewLoginAttemptStore() repo.LoginAttemptStore {
+	return &loginAttemptStore{
+		attempts: make(map[string]repo.LoginAttempts),
+		now:      time.Now,
+	}
+}
+
+func (l *loginAttemptStore) Attempt(ctx context.Context, key string, window time.Duration, limit repo.LoginLimit) (wait time.Duration, locked bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for k, a := range l.attempts {
+		if !now.Before(a.LastFailure.Add(window)) {
+			delete(l.attempts, k)
+		}
+	}
+
+	attempts := l.attempts[key]
+	if wait, locked = limit.RetryAfter(attempts, now); wait > 0 {
+		return
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+	l.attempts[key] = attempts
+	return
+}
+
+func (l *loginAttemptStore) Forgive(ctx context.Context, key string) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempts, ok := l.attempts[key]
+	if !ok {
+		return
+	}
+	attempts.Failures--
+	if attempts.Failures <= 0 {
+		delete(l.attempts, key)
+		return
+	}
+	l.attempts[key] = attempts
+	return
+}
+
+func (l *loginAttemptStore) Reset(ctx context.Context, key string) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+	return
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"be-shop/internal/app/repo"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestStore(now *time.Time) *loginAttemptStore {
+	store := NewLoginAttemptStore().(*loginAttemptStore)
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestAttemptIsAtomic(t *testing.T) {
+	store := NewLoginAttemptStore()
+	limit := repo.LoginLimit{DelayAfter: 3, Delay: time.Hour, MaxDelay: time.Hour}
+
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, _, err := store.Attempt(context.Background(), "user:1", time.Hour, limit)
+			if err != nil {
+				t.Errorf("Attempt: %v", err)
+			}
+			if wait == 0 {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 3 {
+		t.Errorf("allowed %d concurrent attempts, want 3", allowed)
+	}
+}
+
+func TestAttemptLockout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	ctx := context.Background()
+	limit := repo.LoginLimit{MaxFailures: 2, Lockout: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		if wait, locked, _ := store.Attempt(ctx, "user:1", time.Minute, limit); wait != 0 || locked {
+			t.Fatalf("attempt %d refused: %s, %v", i+1, wait, locked)
+		}
+	}
+
+	now = now.Add(10 * time.Second)
+	wait, locked, _ := store.Attempt(ctx, "user:1", time.Minute, limit)
+	if wait != 50*time.Second || !locked {
+		t.Errorf("third attempt = %s, %v, want 50s, locked", wait, locked)
+	}
+	if got := store.attempts["user:1"].Failures; got != 2 {
+		t.Errorf("refused attempt was counted: %d failures, want 2", got)
+	}
+
+	if wait, _, _ := store.Attempt(ctx, "user:2", time.Minute, limit); wait != 0 {
+		t.Errorf("other key refused: %s", wait)
+	}
+
+	// the lockout and the failures behind it lapse together
+	now = now.Add(50 * time.Second)
+	if wait, _, _ := store.Attempt(ctx, "user:1", time.Minute, limit); wait != 0 {
+		t.Errorf("attempt after lockout refused: %s", wait)
+	}
+	if got := store.attempts["user:1"].Failures; got != 1 {
+		t.Errorf("failures after window = %d, want 1", got)
+	}
+
+	now = now.Add(time.Minute)
+	store.Attempt(ctx, "user:1", time.Minute, limit)
+	if _, ok := store.attempts["user:2"]; ok {
+		t.Errorf("expired key user:2 was not pruned")
+	}
+}
+
+func TestForgiveAndReset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	ctx := context.Background()
+	limit := repo.LoginLimit{MaxFailures: 2, Lockout: time.Minute}
+
+	store.Attempt(ctx, "ip:1", time.Minute, limit)
+	store.Attempt(ctx, "ip:1", time.Minute, limit)
+	if err := store.Forgive(ctx, "ip:1"); err != nil {
+		t.Fatalf("Forgive: %v", err)
+	}
+	if wait, _, _ := store.Attempt(ctx, "ip:1", time.Minute, limit); wait != 0 {
+		t.Errorf("attempt after Forgive refused: %s", wait)
+	}
+
+	store.Forgive(ctx, "ip:1")
+	store.Forgive(ctx, "ip:1")
+	if _, ok := store.attempts["ip:1"]; ok {
+		t.Errorf("key with no failures left was kept")
+	}
+	if err := store.Forgive(ctx, "ip:unknown"); err != nil {
+		t.Errorf("Forgive unknown key: %v", err)
+	}
+
+	store.Attempt(ctx, "user:1", time.Minute, limit)
+	store.Attempt(ctx, "user:1", time.Minute, limit)
+	if err := store.Reset(ctx, "user:1"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if wait, locked, _ := store.Attempt(ctx, "user:1", time.Minute, limit); wait != 0 || locked {
+		t.Errorf("attempt after Reset refused: %s, %v", wait, locked)
+	}
+}
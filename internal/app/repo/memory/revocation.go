@@ -0,0 +1,64 @@
+// Package memory provides in-process implementations of the repo interfaces.
+// State is lost on restart and is not shared between instances, so they suit
+// development and single instance deployments.
+package memory
+
+import (
+	"be-shop/internal/app/repo"
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	revocationStore struct {
+		mu     sync.Mutex
+		tokens map[string]time.Time
+		users  map[int]time.Time
+	}
+)
+
+func NewRevocationStore() repo.RevocationStore {
+	return &revocationStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[int]time.Time),
+	}
+}
+
+func (r *revocationStore) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	// a revoked token only needs to be remembered until it would have expired
+	for id, exp := range r.tokens {
+		if !now.Before(exp) {
+			delete(r.tokens, id)
+		}
+	}
+	r.tokens[jti] = expiresAt
+	return
+}
+
+func (r *revocationStore) RevokeUser(ctx context.Context, userID int, before time.Time) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, ok := r.users[userID]; !ok || before.After(current) {
+		r.users[userID] = before
+	}
+	return
+}
+
+func (r *revocationStore) IsRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (revoked bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tokens[jti]; ok {
+		return true, nil
+	}
+	if before, ok := r.users[userID]; ok && issuedAt.Before(before) {
+		return true, nil
+	}
+	return false, nil
+}
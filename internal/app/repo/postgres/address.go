@@ -0,0 +1,277 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres/queries"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"go.uber.org/dig"
+)
+
+type (
+	AddressRepo interface {
+		CreateAddress(ctx context.Context, req models.Address) (address models.Address, err error)
+		GetAddresses(ctx context.Context, userID int64) (addresses []models.Address, err error)
+		GetAddress(ctx context.Context, userID int64, id int) (address models.Address, err error)
+		GetDefaultAddress(ctx context.Context, userID int64) (address models.Address, err error)
+		UpdateAddress(ctx context.Context, req models.Address) (err error)
+		DeleteAddress(ctx context.Context, userID int64, id int) (err error)
+		SetDefaultAddress(ctx context.Context, userID int64, id int) (err error)
+	}
+
+	AddressRepoImpl struct {
+		dig.In
+
+		*sql.DB
+	}
+)
+
+func NewAddressRepo(impl AddressRepoImpl) AddressRepo {
+	return &impl
+}
+
+// CreateAddress stores a new address. The first address a user adds always
+// becomes their default.
+func (a *AddressRepoImpl) CreateAddress(ctx context.Context, req models.Address) (address models.Address, err error) {
+	tx, err := a.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.CreateAddress] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = lockUserAddresses(ctx, tx, int64(req.UserID))
+	if err != nil {
+		return
+	}
+
+	if req.IsDefault {
+		_, err = tx.ExecContext(ctx, queries.QueryClearDefaultAddress, req.UserID, 0)
+		if err != nil {
+			slog.ErrorContext(ctx, "[AddressRepoImpl.CreateAddress] error while ClearDefaultAddress err", "%v", err.Error())
+			return
+		}
+	}
+
+	address = req
+	err = tx.QueryRowContext(ctx, queries.QueryCreateAddress,
+		req.UserID, req.Label, req.RecipientName, req.Phone, req.Line1, req.Line2, req.City, req.Province, req.PostalCode, req.Country,
+		req.IsDefault,
+	).Scan(&address.ID, &address.IsDefault)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.CreateAddress] error while CreateAddress err", "%v", err.Error())
+		return
+	}
+
+	return
+}
+
+func (a *AddressRepoImpl) GetAddresses(ctx context.Context, userID int64) (addresses []models.Address, err error) {
+	rows, err := a.QueryContext(ctx, queries.QueryGetAddresses, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.GetAddresses] error while GetAddresses err", "%v", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	addresses = []models.Address{}
+	for rows.Next() {
+		var address models.Address
+		address, err = scanAddress(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, "[AddressRepoImpl.GetAddresses] error while scan err", "%v", err.Error())
+			return
+		}
+		addresses = append(addresses, address)
+	}
+	err = rows.Err()
+	return
+}
+
+func (a *AddressRepoImpl) GetAddress(ctx context.Context, userID int64, id int) (address models.Address, err error) {
+	address, err = scanAddress(a.QueryRowContext(ctx, queries.QueryGetAddress, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrAddressNotFound
+		}
+		slog.ErrorContext(ctx, "[AddressRepoImpl.GetAddress] error while GetAddress err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (a *AddressRepoImpl) GetDefaultAddress(ctx context.Context, userID int64) (address models.Address, err error) {
+	address, err = scanAddress(a.QueryRowContext(ctx, queries.QueryGetDefaultAddress, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrAddressNotFound
+		}
+		slog.ErrorContext(ctx, "[AddressRepoImpl.GetDefaultAddress] error while GetDefaultAddress err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+// UpdateAddress replaces the address contents. Setting IsDefault moves the
+// default onto this address; clearing it leaves the current default alone,
+// since a user with addresses always has exactly one default.
+func (a *AddressRepoImpl) UpdateAddress(ctx context.Context, req models.Address) (err error) {
+	tx, err := a.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.UpdateAddress] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = lockUserAddresses(ctx, tx, int64(req.UserID))
+	if err != nil {
+		return
+	}
+
+	if req.IsDefault {
+		_, err = tx.ExecContext(ctx, queries.QueryClearDefaultAddress, req.UserID, req.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "[AddressRepoImpl.UpdateAddress] error while ClearDefaultAddress err", "%v", err.Error())
+			return
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, queries.QueryUpdateAddress,
+		req.ID, req.UserID, req.Label, req.RecipientName, req.Phone, req.Line1, req.Line2, req.City, req.Province, req.PostalCode, req.Country,
+		req.IsDefault,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.UpdateAddress] error while UpdateAddress err", "%v", err.Error())
+		return
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = ErrAddressNotFound
+		return
+	}
+
+	return
+}
+
+// DeleteAddress removes an address. When it was the default, the most
+// recently added remaining address takes its place.
+func (a *AddressRepoImpl) DeleteAddress(ctx context.Context, userID int64, id int) (err error) {
+	tx, err := a.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.DeleteAddress] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = lockUserAddresses(ctx, tx, userID)
+	if err != nil {
+		return
+	}
+
+	var wasDefault bool
+	err = tx.QueryRowContext(ctx, queries.QueryDeleteAddress, id, userID).Scan(&wasDefault)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrAddressNotFound
+		}
+		slog.ErrorContext(ctx, "[AddressRepoImpl.DeleteAddress] error while DeleteAddress err", "%v", err.Error())
+		return
+	}
+
+	if wasDefault {
+		_, err = tx.ExecContext(ctx, queries.QueryPromoteDefaultAddress, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "[AddressRepoImpl.DeleteAddress] error while PromoteDefaultAddress err", "%v", err.Error())
+			return
+		}
+	}
+
+	return
+}
+
+func (a *AddressRepoImpl) SetDefaultAddress(ctx context.Context, userID int64, id int) (err error) {
+	tx, err := a.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.SetDefaultAddress] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = lockUserAddresses(ctx, tx, userID)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryClearDefaultAddress, userID, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.SetDefaultAddress] error while ClearDefaultAddress err", "%v", err.Error())
+		return
+	}
+
+	res, err := tx.ExecContext(ctx, queries.QuerySetDefaultAddress, id, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressRepoImpl.SetDefaultAddress] error while SetDefaultAddress err", "%v", err.Error())
+		return
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = ErrAddressNotFound
+		return
+	}
+
+	return
+}
+
+func lockUserAddresses(ctx context.Context, tx *sql.Tx, userID int64) (err error) {
+	var id int64
+	err = tx.QueryRowContext(ctx, queries.QueryLockUserAddresses, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrUserNotFound
+		}
+		slog.ErrorContext(ctx, "[AddressRepoImpl.lockUserAddresses] error while LockUserAddresses err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func scanAddress(row rowScanner) (address models.Address, err error) {
+	err = row.Scan(
+		&address.ID, &address.UserID, &address.Label, &address.RecipientName, &address.Phone, &address.Line1, &address.Line2,
+		&address.City, &address.Province, &address.PostalCode, &address.Country, &address.IsDefault, &address.CreatedAt, &address.UpdatedAt,
+	)
+	return
+}
@@ -3,8 +3,10 @@ package postgres
 import (
 	"be-shop/internal/app/models"
 	"be-shop/internal/app/repo/postgres/queries"
+	"be-shop/pkg/money"
 	"context"
 	"database/sql"
+	"errors"
 	"log/slog"
 
 	"go.uber.org/dig"
@@ -14,6 +16,8 @@ type (
 	CartRepo interface {
 		CreateCart(ctx context.Context, req models.Cart) (id int, err error)
 		GetCartByUserID(ctx context.Context, userID int64) (resp []models.Cart, err error)
+		GetCartQuantityByProductID(ctx context.Context, userID, productID int64) (quantity int, err error)
+		GetCartItemProductID(ctx context.Context, userID, id int64) (productID int64, err error)
 		UpdateCartQuantity(ctx context.Context, userID, id int64, quantity int) (err error)
 		DeleteCart(ctx context.Context, userID, id int64) (err error)
 		DeleteAllCart(ctx context.Context, userID int64) (err error)
@@ -31,7 +35,7 @@ func NewCartRepo(impl CartRepoImpl) CartRepo {
 }
 
 func (c *CartRepoImpl) CreateCart(ctx context.Context, req models.Cart) (id int, err error) {
-	row := c.QueryRowContext(ctx, queries.QueryCreateCart, req.UserID, req.ProductID, req.Quantity)
+	row := c.QueryRowContext(ctx, queries.QueryCreateCart, req.UserID, req.ProductID, req.Quantity, req.ProductPrice)
 	err = row.Scan(&id)
 	if err != nil {
 		slog.ErrorContext(ctx, "[CartRepoImpl.CreateCart] error while CreateCart err", "%v", err.Error())
@@ -47,9 +51,11 @@ func (c *CartRepoImpl) GetCartByUserID(ctx context.Context, userID int64) (resp
 		return
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		var cart models.Cart
-		err = rows.Scan(&cart.ID, &cart.UserID, &cart.ProductID, &cart.ProductName, &cart.Quantity)
+		cart, err = scanCart(rows)
 		if err != nil {
 			slog.ErrorContext(ctx, "[CartRepoImpl.GetCartByUserID] error while scan err", "%v", err.Error())
 			return
@@ -60,6 +66,30 @@ func (c *CartRepoImpl) GetCartByUserID(ctx context.Context, userID int64) (resp
 	return
 }
 
+func (c *CartRepoImpl) GetCartQuantityByProductID(ctx context.Context, userID, productID int64) (quantity int, err error) {
+	err = c.QueryRowContext(ctx, queries.QueryGetCartQuantityByProductID, userID, productID).Scan(&quantity)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartRepoImpl.GetCartQuantityByProductID] error while GetCartQuantityByProductID err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+// GetCartItemProductID returns the product held by one of the user's cart
+// lines, or ErrCartItemNotFound when the line is not theirs.
+func (c *CartRepoImpl) GetCartItemProductID(ctx context.Context, userID, id int64) (productID int64, err error) {
+	err = c.QueryRowContext(ctx, queries.QueryGetCartItemProductID, id, userID).Scan(&productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrCartItemNotFound
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartRepoImpl.GetCartItemProductID] error while GetCartItemProductID err", "%v", err.Error())
+		return
+	}
+	return
+}
+
 func (c *CartRepoImpl) UpdateCartQuantity(ctx context.Context, userID, id int64, quantity int) (err error) {
 	_, err = c.ExecContext(ctx, queries.QueryUpdateCartQuantity, quantity, id, userID)
 	if err != nil {
@@ -86,3 +116,17 @@ func (c *CartRepoImpl) DeleteAllCart(ctx context.Context, userID int64) (err err
 	}
 	return
 }
+
+// scanCart reads a row of QueryGetCartByUserID.
+func scanCart(row rowScanner) (cart models.Cart, err error) {
+	var price money.Money
+	err = row.Scan(&cart.ID, &cart.UserID, &cart.ProductID, &cart.ProductName, &cart.CategoryID, &price, &price.Currency, &cart.PriceAtAdd, &cart.ProductDeleted, &cart.Quantity)
+	if err != nil {
+		return
+	}
+	cart.ProductPrice = &price
+	if cart.PriceAtAdd != nil {
+		cart.PriceAtAdd.Currency = price.Currency
+	}
+	return
+}
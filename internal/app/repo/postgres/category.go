@@ -1,16 +1,25 @@
 package postgres
 
 import (
+	"be-shop/internal/app/models"
 	"be-shop/internal/app/repo/postgres/queries"
 	"context"
 	"database/sql"
+	"errors"
+	"log/slog"
 
+	"github.com/lib/pq"
 	"go.uber.org/dig"
 )
 
 type (
 	CategoryRepo interface {
-		CreateCategory(ctx context.Context, name string) (id int, err error)
+		CreateCategory(ctx context.Context, name string, parentID *int) (id int, err error)
+		GetCategories(ctx context.Context) (categories []models.Category, err error)
+		GetCategoryByID(ctx context.Context, id int) (category models.Category, err error)
+		RenameCategory(ctx context.Context, id int, name string) (err error)
+		MoveCategory(ctx context.Context, id int, parentID *int) (err error)
+		DeleteCategory(ctx context.Context, id int) (err error)
 	}
 
 	CategoryRepoImpl struct {
@@ -24,7 +33,193 @@ func NewCategoryRepo(impl CategoryRepoImpl) CategoryRepo {
 	return &impl
 }
 
-func (c *CategoryRepoImpl) CreateCategory(ctx context.Context, name string) (id int, err error) {
-	err = c.QueryRowContext(ctx, queries.QueryCreateCategory, name).Scan(&id)
+func (c *CategoryRepoImpl) CreateCategory(ctx context.Context, name string, parentID *int) (id int, err error) {
+	err = c.QueryRowContext(ctx, queries.QueryCreateCategory, name, parentID).Scan(&id)
+	if err != nil {
+		err = categoryError(err)
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.CreateCategory] error while CreateCategory err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+// GetCategories returns every category as a flat list ordered by name;
+// models.CategoryTree arranges it into the tree.
+func (c *CategoryRepoImpl) GetCategories(ctx context.Context) (categories []models.Category, err error) {
+	rows, err := c.QueryContext(ctx, queries.QueryGetCategories)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.GetCategories] error while GetCategories err", "%v", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	categories = make([]models.Category, 0)
+	for rows.Next() {
+		var category models.Category
+		category, err = scanCategory(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, "[CategoryRepoImpl.GetCategories] error while scan err", "%v", err.Error())
+			return
+		}
+		categories = append(categories, category)
+	}
+	err = rows.Err()
+	return
+}
+
+func (c *CategoryRepoImpl) GetCategoryByID(ctx context.Context, id int) (category models.Category, err error) {
+	category, err = scanCategory(c.QueryRowContext(ctx, queries.QueryGetCategoryByID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrCategoryNotFound
+		}
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.GetCategoryByID] error while GetCategoryByID err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (c *CategoryRepoImpl) RenameCategory(ctx context.Context, id int, name string) (err error) {
+	res, err := c.ExecContext(ctx, queries.QueryRenameCategory, id, name)
+	if err != nil {
+		err = categoryError(err)
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.RenameCategory] error while RenameCategory err", "%v", err.Error())
+		return
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		err = ErrCategoryNotFound
+	}
+	return
+}
+
+// MoveCategory places a category, with everything below it, under parentID,
+// or at the top level when parentID is nil. Moving a category under itself
+// or one of its descendants fails with ErrCategoryCycle.
+func (c *CategoryRepoImpl) MoveCategory(ctx context.Context, id int, parentID *int) (err error) {
+	tx, err := c.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.MoveCategory] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	_, err = tx.ExecContext(ctx, queries.QueryLockCategories)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.MoveCategory] error while LockCategories err", "%v", err.Error())
+		return
+	}
+
+	if parentID != nil {
+		var cycle bool
+		err = tx.QueryRowContext(ctx, queries.QueryIsCategoryInSubtree, id, *parentID).Scan(&cycle)
+		if err != nil {
+			slog.ErrorContext(ctx, "[CategoryRepoImpl.MoveCategory] error while IsCategoryInSubtree err", "%v", err.Error())
+			return
+		}
+		if cycle {
+			err = ErrCategoryCycle
+			return
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, queries.QueryMoveCategory, id, parentID)
+	if err != nil {
+		err = categoryError(err)
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.MoveCategory] error while MoveCategory err", "%v", err.Error())
+		return
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		err = ErrCategoryNotFound
+		return
+	}
+
+	return
+}
+
+// DeleteCategory removes an empty category. Categories that still have
+// subcategories or products, deleted products included, are refused rather
+// than taking them down with them.
+func (c *CategoryRepoImpl) DeleteCategory(ctx context.Context, id int) (err error) {
+	tx, err := c.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.DeleteCategory] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	var hasChildren, hasProducts bool
+	err = tx.QueryRowContext(ctx, queries.QueryGetCategoryUsage, id).Scan(&hasChildren, &hasProducts)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.DeleteCategory] error while GetCategoryUsage err", "%v", err.Error())
+		return
+	}
+	switch {
+	case hasChildren:
+		err = ErrCategoryHasChildren
+		return
+	case hasProducts:
+		err = ErrCategoryHasProducts
+		return
+	}
+
+	res, err := tx.ExecContext(ctx, queries.QueryDeleteCategory, id)
+	if err != nil {
+		// a child or product added since the usage check
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			err = ErrCategoryHasChildren
+			if pqErr.Table == "products" {
+				err = ErrCategoryHasProducts
+			}
+		}
+		slog.ErrorContext(ctx, "[CategoryRepoImpl.DeleteCategory] error while DeleteCategory err", "%v", err.Error())
+		return
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		err = ErrCategoryNotFound
+		return
+	}
+
+	return
+}
+
+// categoryError maps constraint violations on inserting or updating a
+// category: a duplicate name among its siblings, or a parent that does not
+// exist.
+func categoryError(err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return err
+	}
+	switch pqErr.Code {
+	case "23505":
+		return ErrCategoryNameTaken
+	case "23503":
+		return ErrCategoryNotFound
+	}
+	return err
+}
+
+func scanCategory(row rowScanner) (category models.Category, err error) {
+	var parentID sql.NullInt64
+	err = row.Scan(&category.ID, &parentID, &category.Name, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		category.ParentID = &id
+	}
 	return
 }
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrProductNotFound     = errors.New("product not found")
+	ErrNegativeStock       = errors.New("stock cannot be negative")
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderExpired        = errors.New("order has expired")
+	ErrOrderStatusConflict = errors.New("order status changed concurrently")
+	ErrPromotionNotFound   = errors.New("promotion not found")
+	ErrPromotionCodeTaken  = errors.New("promotion code already exists")
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token has expired")
+	ErrRefreshTokenReused   = errors.New("refresh token has already been used")
+
+	ErrUserTokenInvalid = errors.New("token is invalid, expired or already used")
+	ErrUsernameTaken    = errors.New("username already exists")
+	ErrEmailTaken       = errors.New("email already exists")
+
+	ErrAddressNotFound = errors.New("address not found")
+
+	ErrCartItemNotFound = errors.New("cart item not found")
+
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrCategoryNameTaken   = errors.New("category name already exists under this parent")
+	ErrCategoryCycle       = errors.New("category cannot be moved under itself or its descendants")
+	ErrCategoryHasChildren = errors.New("category still has subcategories")
+	ErrCategoryHasProducts = errors.New("category still has products")
+
+	// ErrTxConflict means a serializable transaction was rolled back because
+	// it raced a concurrent one. Nothing was written; the call can be retried.
+	ErrTxConflict = errors.New("transaction conflicted with a concurrent update")
+)
+
+// InsufficientStockError is returned by Checkout when one or more cart lines
+// ask for more units than the product currently has in stock.
+type InsufficientStockError struct {
+	Items []models.InsufficientStockItem
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for %d cart item(s)", len(e.Items))
+}
+
+// UnavailableProductError is returned by Checkout when the cart holds
+// products that were deleted after being added to it.
+type UnavailableProductError struct {
+	Items []models.UnavailableCartItem
+}
+
+func (e *UnavailableProductError) Error() string {
+	return fmt.Sprintf("%d cart item(s) are no longer available", len(e.Items))
+}
+
+// retryableTxError turns a serialization failure (SQLSTATE 40001), whether
+// raised by a statement or by the commit, into ErrTxConflict.
+func retryableTxError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "40001" {
+		return fmt.Errorf("%w: %s", ErrTxConflict, pqErr.Message)
+	}
+	return err
+}
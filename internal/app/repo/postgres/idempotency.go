@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres/queries"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+type (
+	IdempotencyRepo interface {
+		// Reserve claims req's key. When the key is already taken it returns
+		// the stored record with reserved set to false. A reservation left
+		// without a response for longer than inFlightTimeout is taken over.
+		Reserve(ctx context.Context, req models.IdempotencyKey, retention, inFlightTimeout time.Duration) (resp models.IdempotencyKey, reserved bool, err error)
+		Complete(ctx context.Context, id int, code int, body []byte) (err error)
+		Release(ctx context.Context, id int) (err error)
+		// DeleteExpired removes keys older than the retention window.
+		DeleteExpired(ctx context.Context, retention time.Duration) (deleted int64, err error)
+	}
+
+	IdempotencyRepoImpl struct {
+		dig.In
+
+		*sql.DB
+	}
+)
+
+func NewIdempotencyRepo(impl IdempotencyRepoImpl) IdempotencyRepo {
+	return &impl
+}
+
+func (i *IdempotencyRepoImpl) Reserve(ctx context.Context, req models.IdempotencyKey, retention, inFlightTimeout time.Duration) (resp models.IdempotencyKey, reserved bool, err error) {
+	err = i.QueryRowContext(ctx, queries.QueryReserveIdempotencyKey, req.UserID, req.Key, req.Endpoint, req.RequestHash, retention.Seconds(), inFlightTimeout.Seconds()).Scan(&req.ID)
+	if err == nil {
+		return req, true, nil
+	}
+	if err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "[IdempotencyRepoImpl.Reserve] error while ReserveIdempotencyKey err", "%v", err.Error())
+		return
+	}
+
+	err = i.QueryRowContext(ctx, queries.QueryGetIdempotencyKey, req.UserID, req.Key, req.Endpoint).Scan(
+		&resp.ID, &resp.UserID, &resp.Key, &resp.Endpoint, &resp.RequestHash, &resp.ResponseCode, &resp.ResponseBody,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "[IdempotencyRepoImpl.Reserve] error while GetIdempotencyKey err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (i *IdempotencyRepoImpl) Complete(ctx context.Context, id int, code int, body []byte) (err error) {
+	_, err = i.ExecContext(ctx, queries.QueryCompleteIdempotencyKey, code, body, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[IdempotencyRepoImpl.Complete] error while CompleteIdempotencyKey err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (i *IdempotencyRepoImpl) Release(ctx context.Context, id int) (err error) {
+	_, err = i.ExecContext(ctx, queries.QueryDeleteIdempotencyKey, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[IdempotencyRepoImpl.Release] error while DeleteIdempotencyKey err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (i *IdempotencyRepoImpl) DeleteExpired(ctx context.Context, retention time.Duration) (deleted int64, err error) {
+	res, err := i.ExecContext(ctx, queries.QueryDeleteExpiredIdempotencyKeys, retention.Seconds())
+	if err != nil {
+		slog.ErrorContext(ctx, "[IdempotencyRepoImpl.DeleteExpired] error while DeleteExpiredIdempotencyKeys err", "%v", err.Error())
+		return
+	}
+	deleted, _ = res.RowsAffected()
+	return
+}
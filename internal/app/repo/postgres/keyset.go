@@ -0,0 +1,44 @@
+package postgres
+
+import "be-shop/internal/app/models"
+
+// keysetPage turns the rows of a keyset query into a page. The query fetches
+// limit+1 rows in the cursor's direction so the extra row reveals whether
+// there is more beyond the page; a backward page arrives in reverse and is
+// flipped into display order here. key returns the cursor for one row.
+func keysetPage[T any](rows []T, cursor models.Cursor, limit int, key func(T) models.Cursor) ([]T, models.CursorPage) {
+	var page models.CursorPage
+
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	if cursor.Backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	first, last := key(rows[0]), key(rows[len(rows)-1])
+	first.Backward = true
+
+	// a backward page was reached from the page after it, and a forward page
+	// from any cursor but the start has the page before it
+	if cursor.Backward {
+		page.Next = last.Encode()
+		if more {
+			page.Prev = first.Encode()
+		}
+	} else {
+		if more {
+			page.Next = last.Encode()
+		}
+		if !cursor.IsStart() {
+			page.Prev = first.Encode()
+		}
+	}
+	return rows, page
+}
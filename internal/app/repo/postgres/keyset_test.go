@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"reflect"
+	"testing"
+)
+
+func TestKeysetPage(t *testing.T) {
+	key := func(id int) models.Cursor {
+		return models.Cursor{Key: "k", ID: int64(id)}
+	}
+	next := func(id int) string {
+		return key(id).Encode()
+	}
+	prev := func(id int) string {
+		c := key(id)
+		c.Backward = true
+		return c.Encode()
+	}
+
+	tests := []struct {
+		name     string
+		rows     []int
+		cursor   models.Cursor
+		wantRows []int
+		wantPage models.CursorPage
+	}{
+		{name: "empty start", rows: []int{}, wantRows: []int{}},
+		{name: "start, one page", rows: []int{9, 8}, wantRows: []int{9, 8}},
+		{name: "start, more", rows: []int{9, 8, 7, 6}, wantRows: []int{9, 8, 7}, wantPage: models.CursorPage{Next: next(7)}},
+		{name: "forward, more", rows: []int{6, 5, 4, 3}, cursor: key(7), wantRows: []int{6, 5, 4}, wantPage: models.CursorPage{Next: next(4), Prev: prev(6)}},
+		{name: "forward, last page", rows: []int{3, 2}, cursor: key(4), wantRows: []int{3, 2}, wantPage: models.CursorPage{Prev: prev(3)}},
+		{name: "forward, past the end", rows: []int{}, cursor: key(1), wantRows: []int{}},
+		{name: "backward, more", rows: []int{7, 8, 9, 10}, cursor: models.Cursor{Key: "k", ID: 6, Backward: true}, wantRows: []int{9, 8, 7}, wantPage: models.CursorPage{Next: next(7), Prev: prev(9)}},
+		{name: "backward, first page", rows: []int{7, 8}, cursor: models.Cursor{Key: "k", ID: 6, Backward: true}, wantRows: []int{8, 7}, wantPage: models.CursorPage{Next: next(7)}},
+	}
+
+	for _, tt := range tests {
+		rows, page := keysetPage(tt.rows, tt.cursor, 3, key)
+		if !reflect.DeepEqual(rows, tt.wantRows) {
+			t.Errorf("%s: rows = %v, want %v", tt.name, rows, tt.wantRows)
+		}
+		if page != tt.wantPage {
+			t.Errorf("%s: page = %+v, want %+v", tt.name, page, tt.wantPage)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres/queries"
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"go.uber.org/dig"
+)
+
+type (
+	OrderRepo interface {
+		GetOrdersByUserID(ctx context.Context, userID int64, filter models.OrderFilter) (totalItem int, orders []models.Order, err error)
+		GetOrders(ctx context.Context, filter models.OrderFilter) (totalItem int, orders []models.Order, err error)
+		GetOrdersByCursor(ctx context.Context, filter models.OrderFilter, cursor models.Cursor) (orders []models.Order, page models.CursorPage, err error)
+		GetOrderByOrderCode(ctx context.Context, userID int64, orderCode string) (order models.Order, err error)
+		FindOrderByOrderCode(ctx context.Context, orderCode string) (order models.Order, err error)
+		GetExpiredOrderIDs(ctx context.Context, limit int) (ids []int64, err error)
+		ExpireOrder(ctx context.Context, transition models.OrderTransition) (err error)
+		UpdateOrderStatus(ctx context.Context, transition models.OrderTransition) (err error)
+		CancelOrder(ctx context.Context, transition models.OrderTransition, refund *models.Refund) (err error)
+		CompleteRefund(ctx context.Context, transition models.OrderTransition, providerReference string) (err error)
+	}
+
+	OrderRepoImpl struct {
+		dig.In
+
+		*sql.DB
+	}
+)
+
+func NewOrderRepo(impl OrderRepoImpl) OrderRepo {
+	return &impl
+}
+
+func (o *OrderRepoImpl) GetOrdersByUserID(ctx context.Context, userID int64, filter models.OrderFilter) (totalItem int, orders []models.Order, err error) {
+	filter.UserID = userID
+	return o.GetOrders(ctx, filter)
+}
+
+func (o *OrderRepoImpl) GetOrders(ctx context.Context, filter models.OrderFilter) (totalItem int, orders []models.Order, err error) {
+	rows, err := o.QueryContext(ctx, queries.QueryGetOrders, filter.UserID, filter.Status, filter.From, filter.To, filter.Limit, filter.Offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrders] error while GetOrders err", "%v", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var order models.Order
+		order, err = scanOrder(rows, &totalItem)
+		if err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrders] error while scan err", "%v", err.Error())
+			return
+		}
+		orders = append(orders, order)
+	}
+
+	if orders == nil {
+		orders = make([]models.Order, 0)
+	}
+
+	return
+}
+
+// GetOrdersByCursor lists up to filter.Limit orders matching filter from
+// cursor onwards, newest first.
+func (o *OrderRepoImpl) GetOrdersByCursor(ctx context.Context, filter models.OrderFilter, cursor models.Cursor) (orders []models.Order, page models.CursorPage, err error) {
+	query := queries.QueryGetOrdersAfter
+	if cursor.Backward {
+		query = queries.QueryGetOrdersBefore
+	}
+	var key *string
+	if !cursor.IsStart() {
+		key = &cursor.Key
+	}
+
+	rows, err := o.QueryContext(ctx, query, filter.UserID, filter.Status, filter.From, filter.To, key, cursor.ID, filter.Limit+1)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrdersByCursor] error while GetOrdersByCursor err", "%v", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	orders = make([]models.Order, 0, filter.Limit+1)
+	for rows.Next() {
+		var order models.Order
+		order, err = scanOrder(rows)
+		if err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrdersByCursor] error while scan err", "%v", err.Error())
+			return
+		}
+		orders = append(orders, order)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	orders, page = keysetPage(orders, cursor, filter.Limit, func(order models.Order) models.Cursor {
+		return models.Cursor{Key: order.CreatedAt, ID: int64(order.ID)}
+	})
+	return
+}
+
+func (o *OrderRepoImpl) GetOrderByOrderCode(ctx context.Context, userID int64, orderCode string) (order models.Order, err error) {
+	order, err = scanOrder(o.QueryRowContext(ctx, queries.QueryGetOrderByOrderCode, userID, orderCode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrOrderNotFound
+		}
+		slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrderByOrderCode] error while GetOrderByOrderCode err", "%v", err.Error())
+		return
+	}
+
+	rows, err := o.QueryContext(ctx, queries.QueryGetOrderDetailByOrderID, order.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrderByOrderCode] error while GetOrderDetailByOrderID err", "%v", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	order.Items = make([]models.OrderItem, 0)
+	for rows.Next() {
+		var item models.OrderItem
+		err = rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.ProductName, &item.Quantity, &item.Price, &item.Price.Currency, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrderByOrderCode] error while scan err", "%v", err.Error())
+			return
+		}
+		order.Items = append(order.Items, item)
+	}
+
+	historyRows, err := o.QueryContext(ctx, queries.QueryGetOrderStatusHistory, order.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrderByOrderCode] error while GetOrderStatusHistory err", "%v", err.Error())
+		return
+	}
+	defer historyRows.Close()
+
+	for historyRows.Next() {
+		var history models.OrderStatusHistory
+		err = historyRows.Scan(&history.ID, &history.OrderID, &history.FromStatus, &history.ToStatus, &history.ChangedBy, &history.Note, &history.CreatedAt)
+		if err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.GetOrderByOrderCode] error while scan history err", "%v", err.Error())
+			return
+		}
+		order.History = append(order.History, history)
+	}
+
+	return
+}
+
+// FindOrderByOrderCode looks an order up regardless of who placed it. It does
+// not load items or history.
+func (o *OrderRepoImpl) FindOrderByOrderCode(ctx context.Context, orderCode string) (order models.Order, err error) {
+	order, err = scanOrder(o.QueryRowContext(ctx, queries.QueryGetOrderByCode, orderCode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrOrderNotFound
+		}
+		slog.ErrorContext(ctx, "[OrderRepoImpl.FindOrderByOrderCode] error while GetOrderByCode err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (o *OrderRepoImpl) GetExpiredOrderIDs(ctx context.Context, limit int) (ids []int64, err error) {
+	rows, err := o.QueryContext(ctx, queries.QueryGetExpiredOrderIDs, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.GetExpiredOrderIDs] error while GetExpiredOrderIDs err", "%v", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.GetExpiredOrderIDs] error while scan err", "%v", err.Error())
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	return
+}
+
+func (o *OrderRepoImpl) ExpireOrder(ctx context.Context, transition models.OrderTransition) (err error) {
+	tx, err := o.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.ExpireOrder] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.ExpireOrder] error occurred, rolling back transaction")
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.ExpireOrder] error while commit transaction err", "%v", err.Error())
+		}
+		err = retryableTxError(err)
+	}()
+
+	var (
+		status  models.OrderStatus
+		expired bool
+	)
+	err = tx.QueryRowContext(ctx, queries.QueryGetOrderStatusForUpdate, transition.OrderID).Scan(&status, &expired)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrOrderNotFound
+		}
+		slog.ErrorContext(ctx, "[OrderRepoImpl.ExpireOrder] error while GetOrderStatusForUpdate err", "%v", err.Error())
+		return
+	}
+
+	// the order was paid between listing and locking it
+	if status != transition.From || !expired {
+		err = ErrOrderStatusConflict
+		return
+	}
+
+	err = transitionOrderStatus(ctx, tx, transition)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryReleaseOrderReservations, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.ExpireOrder] error while ReleaseOrderReservations err", "%v", err.Error())
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryReleasePromotionRedemption, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.ExpireOrder] error while ReleasePromotionRedemption err", "%v", err.Error())
+		return
+	}
+
+	return
+}
+
+func (o *OrderRepoImpl) UpdateOrderStatus(ctx context.Context, transition models.OrderTransition) (err error) {
+	tx, err := o.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.UpdateOrderStatus] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = transitionOrderStatus(ctx, tx, transition)
+	return
+}
+
+// CancelOrder applies a cancellation transition, returns the order's stock
+// and coupon use and, when refund is not nil, records the refund owed for it.
+func (o *OrderRepoImpl) CancelOrder(ctx context.Context, transition models.OrderTransition, refund *models.Refund) (err error) {
+	tx, err := o.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.CancelOrder] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.CancelOrder] error occurred, rolling back transaction")
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			slog.ErrorContext(ctx, "[OrderRepoImpl.CancelOrder] error while commit transaction err", "%v", err.Error())
+		}
+		err = retryableTxError(err)
+	}()
+
+	err = transitionOrderStatus(ctx, tx, transition)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryReleaseOrderReservations, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.CancelOrder] error while ReleaseOrderReservations err", "%v", err.Error())
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryReleasePromotionRedemption, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.CancelOrder] error while ReleasePromotionRedemption err", "%v", err.Error())
+		return
+	}
+
+	if refund == nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryCreateRefund, transition.OrderID, refund.Amount, refund.Reason)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.CancelOrder] error while CreateRefund err", "%v", err.Error())
+		return
+	}
+
+	return
+}
+
+func (o *OrderRepoImpl) CompleteRefund(ctx context.Context, transition models.OrderTransition, providerReference string) (err error) {
+	tx, err := o.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.CompleteRefund] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = transitionOrderStatus(ctx, tx, transition)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryCompleteRefund, transition.OrderID, providerReference)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderRepoImpl.CompleteRefund] error while CompleteRefund err", "%v", err.Error())
+		return
+	}
+
+	return
+}
+
+// scanOrder reads the order columns, after any leading columns the query
+// selects into prefix.
+func scanOrder(row rowScanner, prefix ...interface{}) (order models.Order, err error) {
+	dest := append(prefix,
+		&order.ID, &order.UserID, &order.SubtotalAmount, &order.DiscountAmount, &order.TotalAmount, &order.TotalAmount.Currency,
+		&order.PromotionID, &order.Status, &order.OrderCode, &order.ExpiresAt, &order.PaymentProvider, &order.PaymentReference,
+		&order.ShippingAddress, &order.CreatedAt, &order.UpdatedAt,
+	)
+	err = row.Scan(dest...)
+	if err != nil {
+		return
+	}
+
+	order.SubtotalAmount.Currency = order.TotalAmount.Currency
+	order.DiscountAmount.Currency = order.TotalAmount.Currency
+	return
+}
+
+// transitionOrderStatus moves an order from transition.From to transition.To
+// and records the change in order_status_history. It fails with
+// ErrOrderStatusConflict when the order is no longer in transition.From.
+func transitionOrderStatus(ctx context.Context, tx *sql.Tx, transition models.OrderTransition) (err error) {
+	res, err := tx.ExecContext(ctx, queries.QueryTransitionOrderStatus, transition.To, transition.OrderID, transition.From)
+	if err != nil {
+		slog.ErrorContext(ctx, "[postgres.transitionOrderStatus] error while TransitionOrderStatus err", "%v", err.Error())
+		return
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		err = ErrOrderStatusConflict
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryCreateOrderStatusHistory, transition.OrderID, transition.From, transition.To, transition.ChangedBy, transition.Note)
+	if err != nil {
+		slog.ErrorContext(ctx, "[postgres.transitionOrderStatus] error while CreateOrderStatusHistory err", "%v", err.Error())
+		return
+	}
+
+	return
+}
@@ -3,20 +3,25 @@ package postgres
 import (
 	"be-shop/internal/app/models"
 	"be-shop/internal/app/repo/postgres/queries"
+	"be-shop/pkg/money"
 	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
-	"math"
+	"time"
 
 	"go.uber.org/dig"
 )
 
 type (
 	PaymentRepo interface {
-		Checkout(ctx context.Context, userID int64, orderCode string) (totalAmount float64, err error)
+		Checkout(ctx context.Context, userID int64, orderCode string, expiresAt time.Time, shippingAddress models.Address) (order models.Order, err error)
 		GetPaymentByOrderCode(ctx context.Context, userID int64, orderCode string) (resp models.Order, err error)
-		UpdatePaymentStatus(ctx context.Context, userID int64, orderCode, status string) (err error)
+		FindPaymentByOrderCode(ctx context.Context, orderCode string) (resp models.Order, err error)
+		SetPaymentReference(ctx context.Context, orderCode, provider, reference string) (err error)
+		SettlePayment(ctx context.Context, transition models.OrderTransition) (err error)
+		RecordLatePayment(ctx context.Context, transition models.OrderTransition, provider, reference string, refund models.Refund) (err error)
+		RevertCheckout(ctx context.Context, transition models.OrderTransition) (err error)
 	}
 
 	PaymentRepoImpl struct {
@@ -30,12 +35,12 @@ func NewPaymentRepo(impl PaymentRepoImpl) PaymentRepo {
 	return &impl
 }
 
-func (p *PaymentRepoImpl) Checkout(ctx context.Context, userID int64, orderCode string) (total float64, err error) {
+// Checkout turns the user's cart into a pending order. Stock is reserved and
+// any coupon on the cart is re-validated and redeemed in the same
+// transaction, so the order's discount always matches a counted redemption.
+func (p *PaymentRepoImpl) Checkout(ctx context.Context, userID int64, orderCode string, expiresAt time.Time, shippingAddress models.Address) (order models.Order, err error) {
 
-	var (
-		carts   []models.Cart
-		orderID int
-	)
+	var carts []models.Cart
 	tx, err := p.BeginTx(ctx,
 		&sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
@@ -46,9 +51,10 @@ func (p *PaymentRepoImpl) Checkout(ctx context.Context, userID int64, orderCode
 		if err != nil {
 			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error occurred, rolling back transaction")
 			tx.Rollback()
-			return
+		} else if err = tx.Commit(); err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while commit transaction err", "%v", err.Error())
 		}
-		tx.Commit()
+		err = retryableTxError(err)
 	}()
 
 	rows, err := tx.QueryContext(ctx, queries.QueryGetCartByUserID, userID)
@@ -59,9 +65,10 @@ func (p *PaymentRepoImpl) Checkout(ctx context.Context, userID int64, orderCode
 
 	for rows.Next() {
 		var cart models.Cart
-		err = rows.Scan(&cart.ID, &cart.UserID, &cart.ProductID, &cart.ProductName, &cart.Quantity)
+		cart, err = scanCart(rows)
 		if err != nil {
 			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while scan err", "%v", err.Error())
+			rows.Close()
 			return
 		}
 		carts = append(carts, cart)
@@ -73,29 +80,167 @@ func (p *PaymentRepoImpl) Checkout(ctx context.Context, userID int64, orderCode
 		return
 	}
 
+	var (
+		insufficient []models.InsufficientStockItem
+		unavailable  []models.UnavailableCartItem
+	)
 	for indexCart, cart := range carts {
-		var price float64
-		err = tx.QueryRowContext(ctx, queries.QueryGetPriceByProductID, cart.ProductID).Scan(&price)
+		var (
+			price   money.Money
+			stock   int
+			deleted bool
+		)
+		err = tx.QueryRowContext(ctx, queries.QueryGetProductStockForUpdate, cart.ProductID).Scan(&price, &price.Currency, &stock, &deleted)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while GetProductStockForUpdate err", "%v", err.Error())
+			return
+		}
+		if deleted {
+			unavailable = append(unavailable, models.UnavailableCartItem{
+				CartID:    cart.ID,
+				ProductID: cart.ProductID,
+				Name:      cart.ProductName,
+			})
+			continue
+		}
+		if stock < cart.Quantity {
+			insufficient = append(insufficient, models.InsufficientStockItem{
+				CartID:    cart.ID,
+				ProductID: cart.ProductID,
+				Name:      cart.ProductName,
+				Requested: cart.Quantity,
+				Available: stock,
+			})
+			continue
+		}
+		carts[indexCart].ProductPrice = &price
+		var lineTotal money.Money
+		lineTotal, err = price.Mul(int64(cart.Quantity))
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while line total err", "%v", err.Error())
+			return
+		}
+		order.SubtotalAmount, err = order.SubtotalAmount.Add(lineTotal)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while summing total err", "%v", err.Error())
+			return
+		}
+	}
+
+	if len(unavailable) > 0 {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while checking products", "%v", "product deleted")
+		err = &UnavailableProductError{Items: unavailable}
+		return
+	}
+
+	if len(insufficient) > 0 {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while checking stock", "%v", "insufficient stock")
+		err = &InsufficientStockError{Items: insufficient}
+		return
+	}
+
+	for _, cart := range carts {
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, queries.QueryDecrementProductStock, cart.Quantity, cart.ProductID)
 		if err != nil {
-			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while GetPriceByProductID err", "%v", err.Error())
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while DecrementProductStock err", "%v", err.Error())
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			err = &InsufficientStockError{Items: []models.InsufficientStockItem{{
+				CartID:    cart.ID,
+				ProductID: cart.ProductID,
+				Name:      cart.ProductName,
+				Requested: cart.Quantity,
+			}}}
 			return
 		}
-		carts[indexCart].ProductPrice = price
-		total += math.Ceil(price * float64(cart.Quantity))
 	}
 
-	err = tx.QueryRowContext(ctx, queries.QueryCreateOrder, userID, total, orderCode).Scan(&orderID)
+	order.DiscountAmount = money.Money{Currency: order.SubtotalAmount.Currency}
+	promotion, err := scanPromotion(tx.QueryRowContext(ctx, queries.QueryGetCartPromotionForUpdate, userID))
+	switch {
+	case err == sql.ErrNoRows:
+		err = nil
+	case err != nil:
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while GetCartPromotionForUpdate err", "%v", err.Error())
+		return
+	default:
+		var redeemed int
+		err = tx.QueryRowContext(ctx, queries.QueryCountUserRedemptions, promotion.ID, userID).Scan(&redeemed)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while CountUserRedemptions err", "%v", err.Error())
+			return
+		}
+
+		err = promotion.CheckAvailability(time.Now(), redeemed)
+		if err != nil {
+			return
+		}
+
+		order.DiscountAmount, err = promotion.Discount(carts)
+		if err != nil {
+			return
+		}
+		order.PromotionID = &promotion.ID
+	}
+
+	order.TotalAmount, err = order.SubtotalAmount.Sub(order.DiscountAmount)
+	if err != nil {
+		return
+	}
+
+	order.UserID = int(userID)
+	order.OrderCode = orderCode
+	order.Status = models.OrderStatusPending
+	order.ExpiresAt = &expiresAt
+	order.ShippingAddress = &shippingAddress
+	err = tx.QueryRowContext(ctx, queries.QueryCreateOrder,
+		userID, order.SubtotalAmount, order.DiscountAmount, order.TotalAmount, order.TotalAmount.Currency, order.PromotionID, orderCode, expiresAt,
+		shippingAddress,
+	).Scan(&order.ID)
 	if err != nil {
 		slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while CreateOrder err", "%v", err.Error())
 		return
 	}
 
+	if order.PromotionID != nil {
+		_, err = tx.ExecContext(ctx, queries.QueryCreatePromotionRedemption, promotion.ID, userID, order.ID, order.DiscountAmount)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while CreatePromotionRedemption err", "%v", err.Error())
+			return
+		}
+
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, queries.QueryIncrementPromotionUsage, promotion.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while IncrementPromotionUsage err", "%v", err.Error())
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			err = models.ErrPromotionUsageLimit
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, queries.QueryDeleteCartCoupon, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while DeleteCartCoupon err", "%v", err.Error())
+			return
+		}
+	}
+
 	for _, cart := range carts {
-		_, err = tx.ExecContext(ctx, queries.QueryCreateOrderDetail, orderID, cart.ProductID, cart.Quantity, cart.ProductPrice)
+		_, err = tx.ExecContext(ctx, queries.QueryCreateOrderDetail, order.ID, cart.ProductID, cart.Quantity, cart.ProductPrice)
 		if err != nil {
 			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while CreateOrderDetail err", "%v", err.Error())
 			return
 		}
+
+		_, err = tx.ExecContext(ctx, queries.QueryCreateStockReservation, order.ID, cart.ProductID, cart.Quantity, expiresAt)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.Checkout] error while CreateStockReservation err", "%v", err.Error())
+			return
+		}
 	}
 
 	_, err = tx.ExecContext(ctx, queries.QueryDeleteAllCart, userID)
@@ -109,7 +254,7 @@ func (p *PaymentRepoImpl) Checkout(ctx context.Context, userID int64, orderCode
 }
 
 func (p *PaymentRepoImpl) GetPaymentByOrderCode(ctx context.Context, userID int64, orderCode string) (resp models.Order, err error) {
-	err = p.QueryRowContext(ctx, queries.QueryGetOrderByOrderCode, userID, orderCode).Scan(&resp.ID, &resp.UserID, &resp.TotalAmount, &resp.Status, &resp.OrderCode, &resp.CreatedAt, &resp.UpdatedAt)
+	resp, err = scanOrder(p.QueryRowContext(ctx, queries.QueryGetOrderByOrderCode, userID, orderCode))
 	if err != nil {
 		slog.ErrorContext(ctx, "[PaymentRepoImpl.GetPaymentByOrderCode] error while GetOrderByOrderCode err", "%v", err.Error())
 		return
@@ -117,11 +262,176 @@ func (p *PaymentRepoImpl) GetPaymentByOrderCode(ctx context.Context, userID int6
 	return
 }
 
-func (p *PaymentRepoImpl) UpdatePaymentStatus(ctx context.Context, userID int64, orderCode, status string) (err error) {
-	_, err = p.ExecContext(ctx, queries.QueryUpdateOrderStatus, status, orderCode, userID)
+func (p *PaymentRepoImpl) FindPaymentByOrderCode(ctx context.Context, orderCode string) (resp models.Order, err error) {
+	resp, err = scanOrder(p.QueryRowContext(ctx, queries.QueryGetOrderByCode, orderCode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrOrderNotFound
+		}
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.FindPaymentByOrderCode] error while GetOrderByCode err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (p *PaymentRepoImpl) SetPaymentReference(ctx context.Context, orderCode, provider, reference string) (err error) {
+	_, err = p.ExecContext(ctx, queries.QuerySetOrderPaymentReference, provider, reference, orderCode)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.SetPaymentReference] error while SetOrderPaymentReference err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (p *PaymentRepoImpl) SettlePayment(ctx context.Context, transition models.OrderTransition) (err error) {
+	tx, err := p.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.SettlePayment] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.SettlePayment] error occurred, rolling back transaction")
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.SettlePayment] error while commit transaction err", "%v", err.Error())
+		}
+		err = retryableTxError(err)
+	}()
+
+	var (
+		status  models.OrderStatus
+		expired bool
+	)
+	err = tx.QueryRowContext(ctx, queries.QueryGetOrderStatusForUpdate, transition.OrderID).Scan(&status, &expired)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrOrderNotFound
+		}
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.SettlePayment] error while GetOrderStatusForUpdate err", "%v", err.Error())
+		return
+	}
+
+	// the reservation window has passed even if the expiry worker has not
+	// picked the order up yet
+	if status == models.OrderStatusPending && expired {
+		err = ErrOrderExpired
+		return
+	}
+
+	err = transitionOrderStatus(ctx, tx, transition)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryCommitStockReservations, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.SettlePayment] error while CommitStockReservations err", "%v", err.Error())
+		return
+	}
+
+	return
+}
+
+// RecordLatePayment books a payment that arrived after its order was closed
+// unpaid and records the refund owed for it. An order that is still Pending
+// past its reservation window is closed here, handing its stock back first.
+func (p *PaymentRepoImpl) RecordLatePayment(ctx context.Context, transition models.OrderTransition, provider, reference string, refund models.Refund) (err error) {
+	tx, err := p.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.RecordLatePayment] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.RecordLatePayment] error occurred, rolling back transaction")
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.RecordLatePayment] error while commit transaction err", "%v", err.Error())
+		}
+		err = retryableTxError(err)
+	}()
+
+	if transition.From == models.OrderStatusPending {
+		_, err = tx.ExecContext(ctx, queries.QueryReleaseOrderReservations, transition.OrderID)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.RecordLatePayment] error while ReleaseOrderReservations err", "%v", err.Error())
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, queries.QueryReleasePromotionRedemption, transition.OrderID)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.RecordLatePayment] error while ReleasePromotionRedemption err", "%v", err.Error())
+			return
+		}
+	}
+
+	err = transitionOrderStatus(ctx, tx, transition)
 	if err != nil {
-		slog.ErrorContext(ctx, "[PaymentRepoImpl.UpdatePaymentStatus] error while UpdateOrderStatus err", "%v", err.Error())
 		return
 	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryRecordOrderPayment, provider, reference, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.RecordLatePayment] error while RecordOrderPayment err", "%v", err.Error())
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryCreateRefund, transition.OrderID, refund.Amount, refund.Reason)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.RecordLatePayment] error while CreateRefund err", "%v", err.Error())
+		return
+	}
+
+	return
+}
+
+// RevertCheckout cancels an order that could not be charged and puts its
+// lines and coupon back into the cart, undoing Checkout.
+func (p *PaymentRepoImpl) RevertCheckout(ctx context.Context, transition models.OrderTransition) (err error) {
+	tx, err := p.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.RevertCheckout] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.RevertCheckout] error occurred, rolling back transaction")
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			slog.ErrorContext(ctx, "[PaymentRepoImpl.RevertCheckout] error while commit transaction err", "%v", err.Error())
+		}
+		err = retryableTxError(err)
+	}()
+
+	err = transitionOrderStatus(ctx, tx, transition)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryReleaseOrderReservations, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.RevertCheckout] error while ReleaseOrderReservations err", "%v", err.Error())
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryReleasePromotionRedemption, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.RevertCheckout] error while ReleasePromotionRedemption err", "%v", err.Error())
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryRestoreCartFromOrder, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.RevertCheckout] error while RestoreCartFromOrder err", "%v", err.Error())
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryRestoreCartCouponFromOrder, transition.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentRepoImpl.RevertCheckout] error while RestoreCartCouponFromOrder err", "%v", err.Error())
+		return
+	}
+
 	return
 }
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres/queries"
+	"fmt"
+	"strings"
+)
+
+// productSort is one way of ordering a product listing. key is the column
+// expression the listing is ordered and keyset-paginated on, cast the SQL
+// type a cursor's key is read back as.
+type productSort struct {
+	key  string
+	cast string
+	desc bool
+}
+
+// productSorts whitelists the orderings a product listing may use. Request
+// values are only ever looked up here, never written into the SQL. Every
+// ordering ends on id so pages stay stable when the sort key ties.
+var productSorts = map[string]productSort{
+	models.ProductSortNewest:    {key: "created_at", cast: "timestamp", desc: true},
+	models.ProductSortPriceAsc:  {key: "price", cast: "numeric"},
+	models.ProductSortPriceDesc: {key: "price", cast: "numeric", desc: true},
+	models.ProductSortNameAsc:   {key: "LOWER(name)", cast: "text"},
+	models.ProductSortNameDesc:  {key: "LOWER(name)", cast: "text", desc: true},
+}
+
+func lookupProductSort(sort string) productSort {
+	if s, ok := productSorts[sort]; ok {
+		return s
+	}
+	return productSorts[models.ProductSortNewest]
+}
+
+// orderBy returns the ORDER BY clause, reversed when reading backwards from
+// a cursor.
+func (s productSort) orderBy(backward bool) string {
+	direction := "ASC"
+	if s.desc != backward {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", s.key, direction, direction)
+}
+
+// likeEscaper escapes the LIKE wildcards so a search term is matched
+// literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// productListQuery builds the WHERE clause and arguments for a product
+// listing. Conditions are fixed strings; only their arguments come from the
+// filter.
+type productListQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+func newProductListQuery(filter models.ProductFilter) *productListQuery {
+	q := &productListQuery{conditions: []string{"deleted_at IS NULL"}}
+	if filter.Deleted {
+		q.conditions[0] = "deleted_at IS NOT NULL"
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		q.where("name ILIKE '%%' || $%d || '%%'", likeEscaper.Replace(search))
+	}
+	if filter.CategoryID != 0 {
+		// a category includes everything filed under its subcategories
+		q.where("category_id IN ("+queries.SubqueryCategorySubtree+")", filter.CategoryID)
+	}
+	if filter.MinPrice != nil {
+		q.where("price >= $%d", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		q.where("price <= $%d", *filter.MaxPrice)
+	}
+	return q
+}
+
+// where adds a condition whose single %d is replaced by the placeholder
+// number of arg.
+func (q *productListQuery) where(condition string, arg interface{}) {
+	q.args = append(q.args, arg)
+	q.conditions = append(q.conditions, fmt.Sprintf(condition, len(q.args)))
+}
+
+func (q *productListQuery) whereClause() string {
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// Count returns the query counting every product that matches the filter.
+func (q *productListQuery) Count() (query string, args []interface{}) {
+	return queries.QueryCountProducts + q.whereClause(), q.args
+}
+
+// Select returns the query for one sorted page of matching products.
+func (q *productListQuery) Select(sort string, limit, offset int) (query string, args []interface{}) {
+	args = append(append([]interface{}{}, q.args...), limit, offset)
+	query = fmt.Sprintf("%s%s ORDER BY %s LIMIT $%d OFFSET $%d",
+		queries.QuerySelectProducts, q.whereClause(), lookupProductSort(sort).orderBy(false), len(args)-1, len(args))
+	return
+}
+
+// SelectFrom returns the query for up to limit matching products after
+// cursor, or before it when the cursor points backwards. Each row leads with
+// its sort key as text, for building the cursors of the page.
+func (q *productListQuery) SelectFrom(cursor models.Cursor, limit int) (query string, args []interface{}) {
+	sort := lookupProductSort(cursor.Sort)
+	page := &productListQuery{
+		conditions: append([]string{}, q.conditions...),
+		args:       append([]interface{}{}, q.args...),
+	}
+	if !cursor.IsStart() {
+		op := ">"
+		if sort.desc != cursor.Backward {
+			op = "<"
+		}
+		page.args = append(page.args, cursor.Key)
+		page.conditions = append(page.conditions,
+			fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)", sort.key, op, len(page.args), sort.cast, len(page.args)+1))
+		page.args = append(page.args, cursor.ID)
+	}
+
+	args = append(page.args, limit)
+	query = fmt.Sprintf("%s%s ORDER BY %s LIMIT $%d",
+		fmt.Sprintf(queries.QuerySelectProductsByKey, sort.key), page.whereClause(), sort.orderBy(cursor.Backward), len(args))
+	return
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"be-shop/internal/app/models"
 	"be-shop/internal/app/repo/postgres/queries"
+	"be-shop/pkg/money"
 	"context"
 	"database/sql"
 	"fmt"
@@ -15,10 +16,15 @@ type (
 	ProductRepo interface {
 		CreateProduct(ctx context.Context, req models.Product) (id int, err error)
 		GetProductByID(ctx context.Context, id int64) (product models.Product, err error)
-		GetAllProduct(ctx context.Context, page, limit int) (totalItem int, products []models.Product, err error)
+		GetAllProduct(ctx context.Context, filter models.ProductFilter) (totalItem int, products []models.Product, err error)
+		GetProductsByCursor(ctx context.Context, filter models.ProductFilter, cursor models.Cursor) (products []models.Product, page models.CursorPage, err error)
 		GetProductByCategoryID(ctx context.Context, id int64) (resp []models.Product, err error)
-		UpdateProductPrice(ctx context.Context, id int64, price float64) (err error)
+		SearchProducts(ctx context.Context, query string, limit, offset int) (totalItem int, results []models.ProductSearchResult, err error)
+		UpdateProductPrice(ctx context.Context, id int64, price money.Money) (err error)
 		SoftDeleteProduct(ctx context.Context, id int64) (err error)
+		RestoreProduct(ctx context.Context, id int64) (err error)
+		AdjustStock(ctx context.Context, id int64, delta int, reason string) (resp models.StockAdjustment, err error)
+		GetStockAdjustments(ctx context.Context, id int64) (resp []models.StockAdjustment, err error)
 	}
 
 	ProductRepoImpl struct {
@@ -33,7 +39,7 @@ func NewProductRepo(impl ProductRepoImpl) ProductRepo {
 }
 
 func (p *ProductRepoImpl) CreateProduct(ctx context.Context, req models.Product) (id int, err error) {
-	_, err = p.QueryContext(ctx, queries.QueryCreateProduct, req.Name, req.CategoryID, req.Price)
+	err = p.QueryRowContext(ctx, queries.QueryCreateProduct, req.Name, req.Description, req.CategoryID, req.Price, req.Price.Currency, req.Stock).Scan(&id)
 	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.CreateProduct] error while CreateProduct err: %v", err.Error()))
 		return id, err
@@ -44,16 +50,36 @@ func (p *ProductRepoImpl) CreateProduct(ctx context.Context, req models.Product)
 
 func (p *ProductRepoImpl) GetProductByID(ctx context.Context, id int64) (product models.Product, err error) {
 	row := p.QueryRowContext(ctx, queries.QueryGetProductByID, id)
-	err = row.Scan(&product.ID, &product.Name, &product.CategoryID, &product.Price, &product.CreatedAt, &product.UpdatedAt)
+	product, err = scanProduct(row)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrProductNotFound
+		}
 		slog.ErrorContext(ctx, fmt.Sprintf("error while GetProductByID err: %v", err.Error()))
 		return
 	}
 	return
 }
 
-func (p *ProductRepoImpl) GetAllProduct(ctx context.Context, page, limit int) (totalItem int, products []models.Product, err error) {
-	rows, err := p.QueryContext(ctx, queries.QueryGetAllProducts, limit, page)
+// GetAllProduct lists one page of products matching filter. totalItem counts
+// every match, not just the returned page.
+func (p *ProductRepoImpl) GetAllProduct(ctx context.Context, filter models.ProductFilter) (totalItem int, products []models.Product, err error) {
+	list := newProductListQuery(filter)
+
+	query, args := list.Count()
+	err = p.QueryRowContext(ctx, query, args...).Scan(&totalItem)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetAllProduct] error while CountProducts err: %v", err.Error()))
+		return
+	}
+
+	products = make([]models.Product, 0)
+	if totalItem == 0 {
+		return
+	}
+
+	query, args = list.Select(filter.Sort, filter.Limit, filter.Offset)
+	rows, err := p.QueryContext(ctx, query, args...)
 	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetAllProduct] error while GetAllProduct err: %v", err.Error()))
 		return
@@ -62,27 +88,60 @@ func (p *ProductRepoImpl) GetAllProduct(ctx context.Context, page, limit int) (t
 
 	for rows.Next() {
 		var product models.Product
-		err = rows.Scan(&totalItem, &product.ID, &product.Name, &product.CategoryID, &product.Price, &product.CreatedAt, &product.UpdatedAt)
+		product, err = scanProduct(rows)
 		if err != nil {
 			slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetAllProduct] error while GetAllProduct err: %v", err.Error()))
 			return
 		}
 		products = append(products, product)
 	}
+	err = rows.Err()
+	return
+}
 
-	if products == nil {
-		products = make([]models.Product, 0)
-		slog.InfoContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetAllProduct] products: %v", products))
+// GetProductsByCursor lists up to filter.Limit products matching filter from
+// cursor onwards, in the order named by cursor.Sort. It skips the count a
+// page-number listing needs, and stays consistent while products are added
+// or removed between pages.
+func (p *ProductRepoImpl) GetProductsByCursor(ctx context.Context, filter models.ProductFilter, cursor models.Cursor) (products []models.Product, page models.CursorPage, err error) {
+	query, args := newProductListQuery(filter).SelectFrom(cursor, filter.Limit+1)
+	rows, err := p.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetProductsByCursor] error while GetProductsByCursor err: %v", err.Error()))
 		return
 	}
+	defer rows.Close()
 
-	slog.InfoContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetAllProduct] totalItem: %v", totalItem))
+	type keyedProduct struct {
+		key     string
+		product models.Product
+	}
+	keyed := make([]keyedProduct, 0, filter.Limit+1)
+	for rows.Next() {
+		var row keyedProduct
+		row.product, err = scanProduct(rows, &row.key)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetProductsByCursor] error while scan err: %v", err.Error()))
+			return
+		}
+		keyed = append(keyed, row)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
 
+	keyed, page = keysetPage(keyed, cursor, filter.Limit, func(row keyedProduct) models.Cursor {
+		return models.Cursor{Sort: cursor.Sort, Key: row.key, ID: int64(row.product.ID)}
+	})
+	products = make([]models.Product, 0, len(keyed))
+	for _, row := range keyed {
+		products = append(products, row.product)
+	}
 	return
 }
 
-func (p *ProductRepoImpl) UpdateProductPrice(ctx context.Context, id int64, price float64) (err error) {
-	_, err = p.ExecContext(ctx, queries.QueryUpdateProductPrice, price, id)
+func (p *ProductRepoImpl) UpdateProductPrice(ctx context.Context, id int64, price money.Money) (err error) {
+	_, err = p.ExecContext(ctx, queries.QueryUpdateProductPrice, price, price.Currency, id)
 	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.UpdateProductPrice] error while UpdateProductPrice err: %v", err.Error()))
 		return err
@@ -90,12 +149,30 @@ func (p *ProductRepoImpl) UpdateProductPrice(ctx context.Context, id int64, pric
 	return
 }
 
+// SoftDeleteProduct hides a product from the catalog. Order history keeps
+// referring to it, and carts holding it show it as no longer available.
 func (p *ProductRepoImpl) SoftDeleteProduct(ctx context.Context, id int64) (err error) {
-	_, err = p.ExecContext(ctx, queries.QueryDeleteProduct, id)
+	res, err := p.ExecContext(ctx, queries.QueryDeleteProduct, id)
 	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.SoftDeleteProduct] error while SoftDeleteProduct err: %v", err.Error()))
 		return err
 	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrProductNotFound
+	}
+	return
+}
+
+// RestoreProduct puts a soft-deleted product back in the catalog.
+func (p *ProductRepoImpl) RestoreProduct(ctx context.Context, id int64) (err error) {
+	res, err := p.ExecContext(ctx, queries.QueryRestoreProduct, id)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.RestoreProduct] error while RestoreProduct err: %v", err.Error()))
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrProductNotFound
+	}
 	return
 }
 
@@ -109,7 +186,7 @@ func (p *ProductRepoImpl) GetProductByCategoryID(ctx context.Context, id int64)
 
 	for rows.Next() {
 		var product models.Product
-		err = rows.Scan(&product.ID, &product.Name, &product.CategoryID, &product.Price, &product.CreatedAt, &product.UpdatedAt)
+		product, err = scanProduct(rows)
 		if err != nil {
 			slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetProductByCategoryID] error while GetProductByCategoryID err: %v", err.Error()))
 			return
@@ -125,3 +202,137 @@ func (p *ProductRepoImpl) GetProductByCategoryID(ctx context.Context, id int64)
 
 	return
 }
+
+func (p *ProductRepoImpl) AdjustStock(ctx context.Context, id int64, delta int, reason string) (resp models.StockAdjustment, err error) {
+	tx, err := p.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.AdjustStock] error while begin transaction err: %v", err.Error()))
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	var (
+		price   money.Money
+		stock   int
+		deleted bool
+	)
+	err = tx.QueryRowContext(ctx, queries.QueryGetProductStockForUpdate, id).Scan(&price, &price.Currency, &stock, &deleted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrProductNotFound
+		}
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.AdjustStock] error while GetProductStockForUpdate err: %v", err.Error()))
+		return
+	}
+
+	if stock+delta < 0 {
+		err = ErrNegativeStock
+		return
+	}
+
+	resp = models.StockAdjustment{
+		ProductID: int(id),
+		Delta:     delta,
+		Reason:    reason,
+	}
+
+	err = tx.QueryRowContext(ctx, queries.QueryAdjustProductStock, delta, id).Scan(&resp.StockAfter)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.AdjustStock] error while AdjustProductStock err: %v", err.Error()))
+		return
+	}
+
+	err = tx.QueryRowContext(ctx, queries.QueryCreateStockAdjustment, id, delta, resp.StockAfter, reason).Scan(&resp.ID, &resp.CreatedAt)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.AdjustStock] error while CreateStockAdjustment err: %v", err.Error()))
+		return
+	}
+
+	return
+}
+
+func (p *ProductRepoImpl) GetStockAdjustments(ctx context.Context, id int64) (resp []models.StockAdjustment, err error) {
+	rows, err := p.QueryContext(ctx, queries.QueryGetStockAdjustmentsByProductID, id)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetStockAdjustments] error while GetStockAdjustmentsByProductID err: %v", err.Error()))
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var adjustment models.StockAdjustment
+		err = rows.Scan(&adjustment.ID, &adjustment.ProductID, &adjustment.Delta, &adjustment.StockAfter, &adjustment.Reason, &adjustment.CreatedAt)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.GetStockAdjustments] error while scan err: %v", err.Error()))
+			return
+		}
+		resp = append(resp, adjustment)
+	}
+
+	if resp == nil {
+		resp = make([]models.StockAdjustment, 0)
+	}
+
+	return
+}
+
+// SearchProducts ranks products by full-text relevance to query. Only when
+// nothing matches that way does it fall back to trigram similarity on the
+// name, so a misspelled query still finds something.
+func (p *ProductRepoImpl) SearchProducts(ctx context.Context, query string, limit, offset int) (totalItem int, results []models.ProductSearchResult, err error) {
+	results = make([]models.ProductSearchResult, 0)
+
+	match, countQuery, searchQuery := models.ProductMatchFullText, queries.QueryCountProductSearch, queries.QuerySearchProducts
+	err = p.QueryRowContext(ctx, countQuery, query).Scan(&totalItem)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.SearchProducts] error while CountProductSearch err: %v", err.Error()))
+		return
+	}
+	if totalItem == 0 {
+		match, countQuery, searchQuery = models.ProductMatchFuzzy, queries.QueryCountFuzzyProductSearch, queries.QueryFuzzyProductSearch
+		err = p.QueryRowContext(ctx, countQuery, query).Scan(&totalItem)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.SearchProducts] error while CountFuzzyProductSearch err: %v", err.Error()))
+			return
+		}
+	}
+	if totalItem == 0 {
+		return
+	}
+
+	rows, err := p.QueryContext(ctx, searchQuery, query, limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.SearchProducts] error while SearchProducts err: %v", err.Error()))
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := models.ProductSearchResult{Match: match}
+		result.Product, err = scanProduct(rows, &result.Rank, &result.Snippet)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("[ProductRepoImpl.SearchProducts] error while scan err: %v", err.Error()))
+			return
+		}
+		results = append(results, result)
+	}
+	err = rows.Err()
+	return
+}
+
+// scanProduct reads the product columns, after any leading columns the query
+// selects into prefix.
+func scanProduct(row rowScanner, prefix ...interface{}) (product models.Product, err error) {
+	dest := append(prefix,
+		&product.ID, &product.Name, &product.Description, &product.CategoryID, &product.Price, &product.Price.Currency, &product.Stock,
+		&product.CreatedAt, &product.UpdatedAt, &product.DeletedAt,
+	)
+	err = row.Scan(dest...)
+	return
+}
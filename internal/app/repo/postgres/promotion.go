@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres/queries"
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/lib/pq"
+	"go.uber.org/dig"
+)
+
+type (
+	PromotionRepo interface {
+		CreatePromotion(ctx context.Context, req models.Promotion) (id int, err error)
+		GetPromotions(ctx context.Context, limit, offset int) (totalItem int, promotions []models.Promotion, err error)
+		GetPromotionByCode(ctx context.Context, code string) (promotion models.Promotion, err error)
+		GetCartPromotion(ctx context.Context, userID int64) (promotion models.Promotion, err error)
+		CountUserRedemptions(ctx context.Context, promotionID int, userID int64) (count int, err error)
+		SetCartCoupon(ctx context.Context, userID int64, promotionID int) (err error)
+		DeleteCartCoupon(ctx context.Context, userID int64) (err error)
+	}
+
+	PromotionRepoImpl struct {
+		dig.In
+
+		*sql.DB
+	}
+
+	rowScanner interface {
+		Scan(dest ...interface{}) error
+	}
+)
+
+func NewPromotionRepo(impl PromotionRepoImpl) PromotionRepo {
+	return &impl
+}
+
+func (p *PromotionRepoImpl) CreatePromotion(ctx context.Context, req models.Promotion) (id int, err error) {
+	tx, err := p.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionRepoImpl.CreatePromotion] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = tx.QueryRowContext(ctx, queries.QueryCreatePromotion,
+		req.Code, req.Description, req.Type, req.Percent, req.Amount, req.MaxDiscount, req.MinSpend, req.MinSpend.Currency,
+		req.UsageLimit, req.PerUserLimit, req.StartsAt, req.EndsAt,
+	).Scan(&id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			err = ErrPromotionCodeTaken
+		}
+		slog.ErrorContext(ctx, "[PromotionRepoImpl.CreatePromotion] error while CreatePromotion err", "%v", err.Error())
+		return
+	}
+
+	for _, productID := range req.ProductIDs {
+		_, err = tx.ExecContext(ctx, queries.QueryCreatePromotionScope, id, productID, nil)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PromotionRepoImpl.CreatePromotion] error while CreatePromotionScope err", "%v", err.Error())
+			return
+		}
+	}
+
+	for _, categoryID := range req.CategoryIDs {
+		_, err = tx.ExecContext(ctx, queries.QueryCreatePromotionScope, id, nil, categoryID)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PromotionRepoImpl.CreatePromotion] error while CreatePromotionScope err", "%v", err.Error())
+			return
+		}
+	}
+
+	return
+}
+
+func (p *PromotionRepoImpl) GetPromotions(ctx context.Context, limit, offset int) (totalItem int, promotions []models.Promotion, err error) {
+	rows, err := p.QueryContext(ctx, queries.QueryGetPromotions, limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionRepoImpl.GetPromotions] error while GetPromotions err", "%v", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var promotion models.Promotion
+		promotion, err = scanPromotion(rows, &totalItem)
+		if err != nil {
+			slog.ErrorContext(ctx, "[PromotionRepoImpl.GetPromotions] error while scan err", "%v", err.Error())
+			return
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	if promotions == nil {
+		promotions = make([]models.Promotion, 0)
+	}
+
+	return
+}
+
+func (p *PromotionRepoImpl) GetPromotionByCode(ctx context.Context, code string) (promotion models.Promotion, err error) {
+	promotion, err = scanPromotion(p.QueryRowContext(ctx, queries.QueryGetPromotionByCode, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrPromotionNotFound
+		}
+		slog.ErrorContext(ctx, "[PromotionRepoImpl.GetPromotionByCode] error while GetPromotionByCode err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+// GetCartPromotion returns the promotion whose coupon is attached to the
+// user's cart, or ErrPromotionNotFound when there is none.
+func (p *PromotionRepoImpl) GetCartPromotion(ctx context.Context, userID int64) (promotion models.Promotion, err error) {
+	promotion, err = scanPromotion(p.QueryRowContext(ctx, queries.QueryGetCartPromotion, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrPromotionNotFound
+			return
+		}
+		slog.ErrorContext(ctx, "[PromotionRepoImpl.GetCartPromotion] error while GetCartPromotion err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (p *PromotionRepoImpl) CountUserRedemptions(ctx context.Context, promotionID int, userID int64) (count int, err error) {
+	err = p.QueryRowContext(ctx, queries.QueryCountUserRedemptions, promotionID, userID).Scan(&count)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionRepoImpl.CountUserRedemptions] error while CountUserRedemptions err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (p *PromotionRepoImpl) SetCartCoupon(ctx context.Context, userID int64, promotionID int) (err error) {
+	_, err = p.ExecContext(ctx, queries.QuerySetCartCoupon, userID, promotionID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionRepoImpl.SetCartCoupon] error while SetCartCoupon err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (p *PromotionRepoImpl) DeleteCartCoupon(ctx context.Context, userID int64) (err error) {
+	_, err = p.ExecContext(ctx, queries.QueryDeleteCartCoupon, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionRepoImpl.DeleteCartCoupon] error while DeleteCartCoupon err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+// scanPromotion reads the promotion columns, after any leading columns the
+// query selects into prefix.
+func scanPromotion(row rowScanner, prefix ...interface{}) (promotion models.Promotion, err error) {
+	var currency string
+	dest := append(prefix,
+		&promotion.ID, &promotion.Code, &promotion.Description, &promotion.Type, &promotion.Percent, &promotion.Amount,
+		&promotion.MaxDiscount, &promotion.MinSpend, &currency, &promotion.UsageLimit, &promotion.PerUserLimit,
+		&promotion.UsedCount, &promotion.StartsAt, &promotion.EndsAt, &promotion.Active,
+		pq.Array(&promotion.ProductIDs), pq.Array(&promotion.CategoryIDs), &promotion.CreatedAt, &promotion.UpdatedAt,
+		pq.Array(&promotion.CategorySubtreeIDs),
+	)
+	err = row.Scan(dest...)
+	if err != nil {
+		return
+	}
+
+	promotion.Amount.Currency = currency
+	promotion.MinSpend.Currency = currency
+	if promotion.MaxDiscount != nil {
+		promotion.MaxDiscount.Currency = currency
+	}
+	return
+}
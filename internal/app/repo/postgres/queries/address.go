@@ -0,0 +1,79 @@
+package queries
+
+const (
+	addressColumns = `
+		id, user_id, label, recipient_name, phone, line1, line2, city, province, postal_code, country, is_default, created_at, updated_at
+	`
+
+	QueryCreateAddress = `
+		INSERT INTO user_addresses (user_id, label, recipient_name, phone, line1, line2, city, province, postal_code, country, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+			$11 OR NOT EXISTS (SELECT 1 FROM user_addresses WHERE user_id = $1))
+		RETURNING id, is_default
+	`
+
+	QueryGetAddresses = `
+		SELECT` + addressColumns + `
+		FROM user_addresses
+		WHERE user_id = $1
+		ORDER BY is_default DESC, created_at DESC, id DESC
+	`
+
+	QueryGetAddress = `
+		SELECT` + addressColumns + `
+		FROM user_addresses
+		WHERE id = $1 AND user_id = $2
+	`
+
+	QueryGetDefaultAddress = `
+		SELECT` + addressColumns + `
+		FROM user_addresses
+		WHERE user_id = $1 AND is_default
+	`
+
+	QueryUpdateAddress = `
+		UPDATE user_addresses
+		SET label = $3, recipient_name = $4, phone = $5, line1 = $6, line2 = $7, city = $8, province = $9, postal_code = $10, country = $11,
+			is_default = is_default OR $12, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`
+
+	QueryDeleteAddress = `
+		DELETE FROM user_addresses
+		WHERE id = $1 AND user_id = $2
+		RETURNING is_default
+	`
+
+	// QueryClearDefaultAddress unsets the current default, except for the
+	// address in $2 which is about to become (or stay) the default.
+	QueryClearDefaultAddress = `
+		UPDATE user_addresses
+		SET is_default = FALSE, updated_at = NOW()
+		WHERE user_id = $1 AND is_default AND id <> $2
+	`
+
+	QuerySetDefaultAddress = `
+		UPDATE user_addresses
+		SET is_default = TRUE, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`
+
+	// QueryPromoteDefaultAddress makes the most recently added address the
+	// default once the previous default has been deleted.
+	QueryPromoteDefaultAddress = `
+		UPDATE user_addresses
+		SET is_default = TRUE, updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM user_addresses
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		)
+	`
+
+	// QueryLockUserAddresses serialises default changes for one user so two
+	// concurrent requests cannot both leave an address marked as default.
+	QueryLockUserAddresses = `
+		SELECT id FROM users WHERE id = $1 FOR UPDATE
+	`
+)
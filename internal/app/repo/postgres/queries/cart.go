@@ -1,10 +1,18 @@
 package queries
 
 const (
-	QueryCreateCart = `INSERT INTO cart_items (user_id, product_id, quantity) VALUES ($1, $2, $3) 
-	ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = cart_items.quantity + $3 RETURNING id`
-	QueryGetCartByUserID = `SELECT c.id, c.user_id, c.product_id, p.name, c.quantity FROM cart_items c
-	JOIN products p ON c.product_id = p.id WHERE c.user_id = $1`
+	// QueryCreateCart adds to an existing line rather than repricing it: the
+	// line keeps the price it was first added at, so the cart can still show
+	// that the price changed since. Lines saved before prices were recorded
+	// take the current one.
+	QueryCreateCart = `INSERT INTO cart_items (user_id, product_id, quantity, price_at_add) VALUES ($1, $2, $3, $4) 
+	ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = cart_items.quantity + $3, price_at_add = COALESCE(cart_items.price_at_add, $4) RETURNING id`
+	QueryGetCartByUserID = `SELECT c.id, c.user_id, c.product_id, p.name, p.category_id, p.price, p.currency, c.price_at_add, p.deleted_at IS NOT NULL, c.quantity FROM cart_items c
+	JOIN products p ON c.product_id = p.id WHERE c.user_id = $1 ORDER BY c.id`
+
+	QueryGetCartQuantityByProductID = `SELECT COALESCE(SUM(quantity), 0) FROM cart_items WHERE user_id = $1 AND product_id = $2`
+
+	QueryGetCartItemProductID = `SELECT product_id FROM cart_items WHERE id = $1 AND user_id = $2`
 
 	QueryUpdateCartQuantity = `UPDATE cart_items SET quantity = $1 WHERE id = $2 AND user_id = $3`
 
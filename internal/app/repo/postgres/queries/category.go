@@ -2,8 +2,71 @@ package queries
 
 const (
 	QueryCreateCategory = `
-		INSERT INTO categories (name)
-		VALUES ($1)
+		INSERT INTO categories (name, parent_id)
+		VALUES ($1, $2)
 		RETURNING id
 		`
+
+	QueryGetCategories = `
+		SELECT id, parent_id, name, created_at, updated_at
+		FROM categories
+		ORDER BY LOWER(name), id
+	`
+
+	QueryGetCategoryByID = `
+		SELECT id, parent_id, name, created_at, updated_at
+		FROM categories
+		WHERE id = $1
+	`
+
+	QueryRenameCategory = `
+		UPDATE categories
+		SET name = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	// QueryLockCategories serialises moves, so two concurrent moves cannot
+	// each pass the cycle check and together form a loop.
+	QueryLockCategories = `
+		LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE
+	`
+
+	// QueryIsCategoryInSubtree reports whether category $2 is $1 or lies
+	// anywhere below it.
+	QueryIsCategoryInSubtree = `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+	`
+
+	QueryMoveCategory = `
+		UPDATE categories
+		SET parent_id = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	QueryGetCategoryUsage = `
+		SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1),
+			EXISTS (SELECT 1 FROM products WHERE category_id = $1)
+	`
+
+	QueryDeleteCategory = `
+		DELETE FROM categories
+		WHERE id = $1
+	`
+
+	// SubqueryCategorySubtree selects the id of a category and of every
+	// category below it. The category id placeholder number is left to be
+	// filled in with fmt, as %[1]d.
+	SubqueryCategorySubtree = `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $%[1]d
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT id FROM subtree
+	`
 )
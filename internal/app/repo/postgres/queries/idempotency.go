@@ -0,0 +1,40 @@
+package queries
+
+const (
+	// QueryReserveIdempotencyKey claims a key for a new request. A key older
+	// than the retention window ($5 seconds), or one still without a response
+	// after the in-flight timeout ($6 seconds), is reclaimed as if it were
+	// new; otherwise nothing is returned and the caller reads the existing row.
+	QueryReserveIdempotencyKey = `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, endpoint, request_hash)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, idempotency_key, endpoint) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, response_code = NULL, response_body = NULL,
+			created_at = NOW(), completed_at = NULL
+		WHERE idempotency_keys.created_at < NOW() - make_interval(secs => $5)
+			OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < NOW() - make_interval(secs => $6))
+		RETURNING id
+	`
+
+	QueryGetIdempotencyKey = `
+		SELECT id, user_id, idempotency_key, endpoint, request_hash, response_code, response_body
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND endpoint = $3
+	`
+
+	QueryCompleteIdempotencyKey = `
+		UPDATE idempotency_keys
+		SET response_code = $1, response_body = $2, completed_at = NOW()
+		WHERE id = $3
+	`
+
+	QueryDeleteExpiredIdempotencyKeys = `
+		DELETE FROM idempotency_keys
+		WHERE created_at < NOW() - make_interval(secs => $1)
+	`
+
+	QueryDeleteIdempotencyKey = `
+		DELETE FROM idempotency_keys
+		WHERE id = $1
+	`
+)
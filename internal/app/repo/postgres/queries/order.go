@@ -0,0 +1,103 @@
+package queries
+
+const (
+	QueryGetOrders = `
+		SELECT COUNT(*) OVER(), id, user_id, subtotal_amount, discount_amount, total_amount, currency, promotion_id, status, order_code, expires_at, payment_provider, payment_reference, shipping_address, created_at, updated_at
+		FROM orders
+		WHERE ($1 = 0 OR user_id = $1)
+			AND ($2 = '' OR status = $2)
+			AND ($3::timestamp IS NULL OR created_at >= $3)
+			AND ($4::timestamp IS NULL OR created_at < $4)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5 OFFSET $6
+	`
+
+	// QueryGetOrdersAfter and QueryGetOrdersBefore read a keyset page of
+	// orders, newest first, either side of the (created_at, id) cursor in $5
+	// and $6. A NULL cursor starts from the newest order.
+	QueryGetOrdersAfter = `
+		SELECT id, user_id, subtotal_amount, discount_amount, total_amount, currency, promotion_id, status, order_code, expires_at, payment_provider, payment_reference, shipping_address, created_at, updated_at
+		FROM orders
+		WHERE ($1 = 0 OR user_id = $1)
+			AND ($2 = '' OR status = $2)
+			AND ($3::timestamp IS NULL OR created_at >= $3)
+			AND ($4::timestamp IS NULL OR created_at < $4)
+			AND ($5::timestamp IS NULL OR (created_at, id) < ($5::timestamp, $6))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $7
+	`
+
+	QueryGetOrdersBefore = `
+		SELECT id, user_id, subtotal_amount, discount_amount, total_amount, currency, promotion_id, status, order_code, expires_at, payment_provider, payment_reference, shipping_address, created_at, updated_at
+		FROM orders
+		WHERE ($1 = 0 OR user_id = $1)
+			AND ($2 = '' OR status = $2)
+			AND ($3::timestamp IS NULL OR created_at >= $3)
+			AND ($4::timestamp IS NULL OR created_at < $4)
+			AND (created_at, id) > ($5::timestamp, $6)
+		ORDER BY created_at ASC, id ASC
+		LIMIT $7
+	`
+
+	QueryGetExpiredOrderIDs = `
+		SELECT id
+		FROM orders
+		WHERE status = 'Pending' AND expires_at <= NOW()
+		ORDER BY expires_at
+		LIMIT $1
+	`
+
+	QueryGetOrderStatusForUpdate = `
+		SELECT status, COALESCE(expires_at <= NOW(), FALSE)
+		FROM orders
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	// QueryTransitionOrderStatus only succeeds while the order is still in
+	// the status the caller validated the transition against.
+	QueryTransitionOrderStatus = `
+		UPDATE orders
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3
+	`
+
+	QueryCreateOrderStatusHistory = `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, note)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	QueryGetOrderStatusHistory = `
+		SELECT id, order_id, from_status, to_status, changed_by, note, created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`
+
+	// QueryReleaseOrderReservations hands every reserved or committed unit of
+	// an order back to product stock in a single statement.
+	QueryReleaseOrderReservations = `
+		WITH released AS (
+			UPDATE stock_reservations
+			SET status = 'Released', updated_at = NOW()
+			WHERE order_id = $1 AND status IN ('Reserved', 'Committed')
+			RETURNING product_id, quantity
+		)
+		UPDATE products p
+		SET stock = p.stock + r.quantity, updated_at = NOW()
+		FROM (SELECT product_id, SUM(quantity) AS quantity FROM released GROUP BY product_id) r
+		WHERE p.id = r.product_id
+	`
+
+	QueryCreateRefund = `
+		INSERT INTO refunds (order_id, amount, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (order_id) DO NOTHING
+	`
+
+	QueryCompleteRefund = `
+		UPDATE refunds
+		SET status = 'Completed', provider_reference = $2, updated_at = NOW()
+		WHERE order_id = $1
+	`
+)
@@ -2,8 +2,8 @@ package queries
 
 const (
 	QueryCreateOrder = `
-		INSERT INTO orders (user_id, total_amount,order_code)
-		VALUES ($1, $2, $3)
+		INSERT INTO orders (user_id, subtotal_amount, discount_amount, total_amount, currency, promotion_id, order_code, expires_at, shipping_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
 	`
 
@@ -13,20 +13,67 @@ const (
 	`
 
 	QueryGetOrderByOrderCode = `
-		SELECT id, user_id, total_amount, status, order_code, created_at, updated_at
+		SELECT id, user_id, subtotal_amount, discount_amount, total_amount, currency, promotion_id, status, order_code, expires_at, payment_provider, payment_reference, shipping_address, created_at, updated_at
 		FROM orders
 		WHERE user_id = $1 AND order_code = $2
 	`
 
+	QueryGetOrderByCode = `
+		SELECT id, user_id, subtotal_amount, discount_amount, total_amount, currency, promotion_id, status, order_code, expires_at, payment_provider, payment_reference, shipping_address, created_at, updated_at
+		FROM orders
+		WHERE order_code = $1
+	`
+
+	QuerySetOrderPaymentReference = `
+		UPDATE orders
+		SET payment_provider = $1, payment_reference = $2, updated_at = NOW()
+		WHERE order_code = $3
+	`
+
+	// QueryRecordOrderPayment keeps the reference set at checkout when the
+	// notification carries none.
+	QueryRecordOrderPayment = `
+		UPDATE orders
+		SET payment_provider = $1, payment_reference = COALESCE(NULLIF($2, ''), payment_reference), updated_at = NOW()
+		WHERE id = $3
+	`
+
+	// QueryRestoreCartFromOrder puts an order's lines back into its owner's
+	// cart, merging with anything added since.
+	QueryRestoreCartFromOrder = `
+		INSERT INTO cart_items (user_id, product_id, quantity, price_at_add)
+		SELECT o.user_id, oi.product_id, oi.quantity, oi.price
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.order_id = $1
+		ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+	`
+
+	QueryRestoreCartCouponFromOrder = `
+		INSERT INTO cart_coupons (user_id, promotion_id)
+		SELECT user_id, promotion_id
+		FROM orders
+		WHERE id = $1 AND promotion_id IS NOT NULL
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
 	QueryGetOrderDetailByOrderID = `
-		SELECT id, order_id, product_id, quantity, price, created_at, updated_at
-		FROM order_items
-		WHERE order_id = $1
+		SELECT oi.id, oi.order_id, oi.product_id, p.name, oi.quantity, oi.price, o.currency, oi.created_at, oi.updated_at
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN products p ON p.id = oi.product_id
+		WHERE oi.order_id = $1
+		ORDER BY oi.id
 		`
 
-	QueryUpdateOrderStatus = `
-		UPDATE orders
-		SET status = $1, updated_at = NOW()
-		WHERE order_code = $2 AND user_id = $3
+	QueryCreateStockReservation = `
+		INSERT INTO stock_reservations (order_id, product_id, quantity, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	QueryCommitStockReservations = `
+		UPDATE stock_reservations
+		SET status = 'Committed', updated_at = NOW()
+		WHERE order_id = $1 AND status = 'Reserved'
 	`
 )
@@ -2,45 +2,148 @@ package queries
 
 const (
 	QueryCreateProduct = `
-		INSERT INTO products (name, category, price)
-		VALUES ($1, $2, $3)
+		INSERT INTO products (name, description, category_id, price, currency, stock)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
 
 	QueryGetProductByID = `
-		SELECT id, name, category_id, price, created_at, updated_at
+		SELECT id, name, description, category_id, price, currency, stock, created_at, updated_at, deleted_at
 		FROM products
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	QueryGetPriceByProductID = `
-		SELECT price
+		SELECT price, currency
 		FROM products
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	QueryGetProductByCategoryID = `
-		SELECT id, name, category_id, price, created_at, updated_at
+		SELECT id, name, description, category_id, price, currency, stock, created_at, updated_at, deleted_at
+		FROM products
+		WHERE category_id IN (
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $1
+				UNION ALL
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+			)
+			SELECT id FROM subtree
+		) AND deleted_at IS NULL
+		ORDER BY created_at DESC, id DESC
+	`
+
+	// The product list queries are completed by the builder in the postgres
+	// package, which appends the WHERE, ORDER BY and LIMIT clauses.
+	QuerySelectProducts = `
+		SELECT id, name, description, category_id, price, currency, stock, created_at, updated_at, deleted_at
+		FROM products
+	`
+
+	// QuerySelectProductsByKey leads each row with the sort key, as text, for
+	// building keyset cursors. The builder fills in the key expression from
+	// its whitelist of sorts.
+	QuerySelectProductsByKey = `
+		SELECT %s::text, id, name, description, category_id, price, currency, stock, created_at, updated_at, deleted_at
+		FROM products
+	`
+
+	QueryCountProducts = `
+		SELECT COUNT(*)
 		FROM products
-		WHERE category_id = $1
 	`
 
-	QueryGetAllProducts = `
-		SELECT COUNT(*) OVER(), id, name, category_id, price, created_at, updated_at
+	// QueryCountProductSearch and QuerySearchProducts match $1 as a web-style
+	// search (quoted phrases, OR, -exclusions) against products.search_vector.
+	// The snippet is HTML: the product text is escaped before ts_headline
+	// adds the <mark> tags, so markup stored in a product is shown as text.
+	QueryCountProductSearch = `
+		SELECT COUNT(*)
 		FROM products
-		LIMIT $1 OFFSET $2
+		WHERE search_vector @@ websearch_to_tsquery('english', $1) AND deleted_at IS NULL
+	`
+
+	QuerySearchProducts = `
+		SELECT ts_rank_cd(p.search_vector, q.query),
+			ts_headline('english',
+				replace(replace(replace(p.name || '. ' || p.description, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				q.query,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter=" ... "'),
+			p.id, p.name, p.description, p.category_id, p.price, p.currency, p.stock, p.created_at, p.updated_at, p.deleted_at
+		FROM products p, websearch_to_tsquery('english', $1) AS q(query)
+		WHERE p.search_vector @@ q.query AND p.deleted_at IS NULL
+		ORDER BY 1 DESC, p.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	// QueryCountFuzzyProductSearch and QueryFuzzyProductSearch catch
+	// misspellings the full-text search misses by comparing trigrams of $1
+	// against product names. The name is escaped to serve as the snippet.
+	QueryCountFuzzyProductSearch = `
+		SELECT COUNT(*)
+		FROM products
+		WHERE (name % $1 OR $1 <% name) AND deleted_at IS NULL
+	`
+
+	QueryFuzzyProductSearch = `
+		SELECT GREATEST(similarity(p.name, $1), word_similarity($1, p.name)),
+			replace(replace(replace(p.name, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+			p.id, p.name, p.description, p.category_id, p.price, p.currency, p.stock, p.created_at, p.updated_at, p.deleted_at
+		FROM products p
+		WHERE (p.name % $1 OR $1 <% p.name) AND p.deleted_at IS NULL
+		ORDER BY 1 DESC, p.id DESC
+		LIMIT $2 OFFSET $3
 	`
 
 	QueryUpdateProductPrice = `
 		UPDATE products
-		SET price = $1, updated_at = NOW()
-		WHERE id = $2
+		SET price = $1, currency = $2, updated_at = NOW()
+		WHERE id = $3
 		RETURNING id
 	`
 
 	QueryDeleteProduct = `
 		UPDATE products
 		SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	QueryRestoreProduct = `
+		UPDATE products
+		SET deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	QueryGetProductStockForUpdate = `
+		SELECT price, currency, stock, deleted_at IS NOT NULL
+		FROM products
 		WHERE id = $1
+		FOR UPDATE
+	`
+
+	QueryDecrementProductStock = `
+		UPDATE products
+		SET stock = stock - $1, updated_at = NOW()
+		WHERE id = $2 AND stock >= $1
+	`
+
+	QueryAdjustProductStock = `
+		UPDATE products
+		SET stock = stock + $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING stock
+	`
+
+	QueryCreateStockAdjustment = `
+		INSERT INTO stock_adjustments (product_id, delta, stock_after, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	QueryGetStockAdjustmentsByProductID = `
+		SELECT id, product_id, delta, stock_after, reason, created_at
+		FROM stock_adjustments
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id DESC
 	`
 )
@@ -0,0 +1,102 @@
+package queries
+
+const (
+	QueryCreatePromotion = `
+		INSERT INTO promotions (code, description, discount_type, discount_percent, discount_amount, max_discount, min_spend, currency, usage_limit, per_user_limit, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`
+
+	QueryCreatePromotionScope = `
+		INSERT INTO promotion_scopes (promotion_id, product_id, category_id)
+		VALUES ($1, $2, $3)
+	`
+
+	// promotionColumns ends the scope with the scoped categories' whole
+	// subtrees, so a coupon for a category also covers its subcategories.
+	promotionColumns = `
+		p.id, p.code, p.description, p.discount_type, p.discount_percent, p.discount_amount, p.max_discount, p.min_spend, p.currency,
+		p.usage_limit, p.per_user_limit, p.used_count, p.starts_at, p.ends_at, p.active,
+		ARRAY(SELECT product_id FROM promotion_scopes WHERE promotion_id = p.id AND product_id IS NOT NULL ORDER BY product_id),
+		ARRAY(SELECT category_id FROM promotion_scopes WHERE promotion_id = p.id AND category_id IS NOT NULL ORDER BY category_id),
+		p.created_at, p.updated_at,
+		ARRAY(
+			WITH RECURSIVE subtree AS (
+				SELECT category_id AS id FROM promotion_scopes WHERE promotion_id = p.id AND category_id IS NOT NULL
+				UNION
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+			)
+			SELECT id FROM subtree ORDER BY id
+		)
+	`
+
+	QueryGetPromotions = `
+		SELECT COUNT(*) OVER(),` + promotionColumns + `
+		FROM promotions p
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	QueryGetPromotionByCode = `
+		SELECT` + promotionColumns + `
+		FROM promotions p
+		WHERE p.code = UPPER($1)
+	`
+
+	QueryGetCartPromotion = `
+		SELECT` + promotionColumns + `
+		FROM cart_coupons c
+		JOIN promotions p ON p.id = c.promotion_id
+		WHERE c.user_id = $1
+	`
+
+	// QueryGetCartPromotionForUpdate locks the promotion so concurrent
+	// checkouts cannot both take its last redemption.
+	QueryGetCartPromotionForUpdate = QueryGetCartPromotion + `
+		FOR UPDATE OF p
+	`
+
+	QueryCountUserRedemptions = `
+		SELECT COUNT(*)
+		FROM promotion_redemptions
+		WHERE promotion_id = $1 AND user_id = $2
+	`
+
+	QuerySetCartCoupon = `
+		INSERT INTO cart_coupons (user_id, promotion_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET promotion_id = EXCLUDED.promotion_id, created_at = NOW()
+	`
+
+	QueryDeleteCartCoupon = `
+		DELETE FROM cart_coupons
+		WHERE user_id = $1
+	`
+
+	QueryCreatePromotionRedemption = `
+		INSERT INTO promotion_redemptions (promotion_id, user_id, order_id, discount_amount)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	// QueryIncrementPromotionUsage only succeeds while the global usage limit
+	// still has room.
+	QueryIncrementPromotionUsage = `
+		UPDATE promotions
+		SET used_count = used_count + 1, updated_at = NOW()
+		WHERE id = $1 AND (usage_limit IS NULL OR used_count < usage_limit)
+	`
+
+	// QueryReleasePromotionRedemption gives a cancelled or expired order's
+	// coupon use back to the promotion.
+	QueryReleasePromotionRedemption = `
+		WITH released AS (
+			DELETE FROM promotion_redemptions
+			WHERE order_id = $1
+			RETURNING promotion_id
+		)
+		UPDATE promotions p
+		SET used_count = p.used_count - 1, updated_at = NOW()
+		FROM released r
+		WHERE p.id = r.promotion_id
+	`
+)
@@ -0,0 +1,44 @@
+package queries
+
+const (
+	QueryCreateRefreshToken = `
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, parent_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	QueryGetRefreshTokenByHashForUpdate = `
+		SELECT id, user_id, token_hash, family_id, parent_id, replaced_by, expires_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+
+	QueryMarkRefreshTokenReplaced = `
+		UPDATE refresh_tokens
+		SET replaced_by = $1, revoked_at = NOW()
+		WHERE id = $2
+	`
+
+	// QueryRevokeRefreshTokenByHash revokes the whole family of the user's
+	// token, so tokens it was already rotated into stop working too.
+	QueryRevokeRefreshTokenByHash = `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE revoked_at IS NULL AND family_id = (
+			SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2
+		)
+	`
+
+	QueryRevokeUserRefreshTokens = `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	QueryRevokeRefreshTokenFamily = `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+)
@@ -0,0 +1,25 @@
+package queries
+
+const (
+	QueryRevokeToken = `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	QueryDeleteExpiredRevokedTokens = `
+		DELETE FROM revoked_tokens
+		WHERE expires_at <= NOW()
+	`
+
+	QueryRevokeUserTokens = `
+		UPDATE users
+		SET tokens_revoked_before = GREATEST(COALESCE(tokens_revoked_before, $1), $1)
+		WHERE id = $2
+	`
+
+	QueryIsTokenRevoked = `
+		SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+			OR EXISTS (SELECT 1 FROM users WHERE id = $2 AND tokens_revoked_before > $3)
+	`
+)
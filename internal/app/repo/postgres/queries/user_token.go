@@ -0,0 +1,22 @@
+package queries
+
+const (
+	// QueryCreateUserToken also invalidates the user's earlier unused tokens
+	// for the same purpose, so only the latest link works.
+	QueryCreateUserToken = `
+		WITH invalidated AS (
+			UPDATE user_tokens
+			SET used_at = NOW()
+			WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+		)
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, new_email)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	QueryGetLastUserTokenAt = `
+		SELECT MAX(created_at)
+		FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2
+	`
+)
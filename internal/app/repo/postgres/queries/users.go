@@ -2,13 +2,80 @@ package queries
 
 const (
 	QueryCreateUser = `
-		INSERT INTO users (email, username, password) VALUES ($1, $2, $3) RETURNING id
+		INSERT INTO users (email, username, password, role) VALUES ($1, $2, $3, $4) RETURNING id
 		`
 	QueryGetUserByEmail = `
 		SELECT id, username, password FROM users WHERE email = $1
 	`
 
+	// QueryGetUserByIdentity matches an email or a username. Usernames cannot
+	// contain @, but older ones might, so an email match wins.
+	QueryGetUserByIdentity = `
+		SELECT id, email, username, password FROM users
+		WHERE email = $1 OR username = $1
+		ORDER BY (email = $1) DESC
+		LIMIT 1
+	`
+
 	QueryGetUserByID = `
-		SELECT id, email, username, password FROM users WHERE id = $1
+		SELECT id, email, username, password, role, email_verified_at FROM users WHERE id = $1
+	`
+
+	// QueryResetPassword consumes a password reset token and sets the new
+	// password in one statement, so a token can never be used twice.
+	QueryResetPassword = `
+		WITH consumed AS (
+			UPDATE user_tokens
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND purpose = 'password_reset' AND used_at IS NULL AND expires_at > NOW()
+			RETURNING user_id
+		)
+		UPDATE users u
+		SET password = $2, updated_at = NOW()
+		FROM consumed c
+		WHERE u.id = c.user_id
+		RETURNING u.id
+	`
+
+	QueryVerifyEmail = `
+		WITH consumed AS (
+			UPDATE user_tokens
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND purpose = 'email_verification' AND used_at IS NULL AND expires_at > NOW()
+			RETURNING user_id
+		)
+		UPDATE users u
+		SET email_verified_at = COALESCE(u.email_verified_at, NOW()), updated_at = NOW()
+		FROM consumed c
+		WHERE u.id = c.user_id
+		RETURNING u.id
+	`
+
+	// QueryConfirmEmailChange consumes an email change token and moves the
+	// user to the address it was issued for, which is verified by the click.
+	QueryConfirmEmailChange = `
+		WITH consumed AS (
+			UPDATE user_tokens
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND purpose = 'email_change' AND used_at IS NULL AND expires_at > NOW()
+			RETURNING user_id, new_email
+		)
+		UPDATE users u
+		SET email = c.new_email, email_verified_at = NOW(), updated_at = NOW()
+		FROM consumed c
+		WHERE u.id = c.user_id
+		RETURNING u.id
+	`
+
+	QueryUpdateUsername = `
+		UPDATE users SET username = $1, updated_at = NOW() WHERE id = $2
+	`
+
+	QueryUpdatePassword = `
+		UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2
+	`
+
+	QueryUpdateUserRole = `
+		UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2
 	`
 )
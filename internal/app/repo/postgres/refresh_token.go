@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres/queries"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+type (
+	RefreshTokenRepo interface {
+		CreateRefreshToken(ctx context.Context, req models.RefreshToken) (id int, err error)
+		// RotateRefreshToken swaps the token stored under tokenHash for next,
+		// which joins the same family. When the token was already rotated or
+		// revoked it returns ErrRefreshTokenReused along with the stored token
+		// so the caller can revoke its family.
+		RotateRefreshToken(ctx context.Context, tokenHash string, next models.RefreshToken) (current models.RefreshToken, err error)
+		RevokeRefreshTokenFamily(ctx context.Context, familyID string) (err error)
+		RevokeRefreshToken(ctx context.Context, userID int, tokenHash string) (err error)
+		RevokeUserRefreshTokens(ctx context.Context, userID int) (err error)
+	}
+
+	RefreshTokenRepoImpl struct {
+		dig.In
+
+		*sql.DB
+	}
+)
+
+func NewRefreshTokenRepo(impl RefreshTokenRepoImpl) RefreshTokenRepo {
+	return &impl
+}
+
+func (r *RefreshTokenRepoImpl) CreateRefreshToken(ctx context.Context, req models.RefreshToken) (id int, err error) {
+	err = r.QueryRowContext(ctx, queries.QueryCreateRefreshToken, req.UserID, req.TokenHash, req.FamilyID, req.ParentID, req.ExpiresAt).Scan(&id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RefreshTokenRepoImpl.CreateRefreshToken] error while CreateRefreshToken err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (r *RefreshTokenRepoImpl) RotateRefreshToken(ctx context.Context, tokenHash string, next models.RefreshToken) (current models.RefreshToken, err error) {
+	tx, err := r.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		slog.ErrorContext(ctx, "[RefreshTokenRepoImpl.RotateRefreshToken] error while begin transaction err", "%v", err.Error())
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = tx.QueryRowContext(ctx, queries.QueryGetRefreshTokenByHashForUpdate, tokenHash).Scan(
+		&current.ID, &current.UserID, &current.TokenHash, &current.FamilyID, &current.ParentID,
+		&current.ReplacedBy, &current.ExpiresAt, &current.RevokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrRefreshTokenNotFound
+			return
+		}
+		slog.ErrorContext(ctx, "[RefreshTokenRepoImpl.RotateRefreshToken] error while GetRefreshTokenByHash err", "%v", err.Error())
+		return
+	}
+
+	switch {
+	case current.ReplacedBy != nil, current.RevokedAt != nil:
+		err = ErrRefreshTokenReused
+		return
+	case !time.Now().Before(current.ExpiresAt):
+		err = ErrRefreshTokenExpired
+		return
+	}
+
+	var nextID int
+	err = tx.QueryRowContext(ctx, queries.QueryCreateRefreshToken, current.UserID, next.TokenHash, current.FamilyID, current.ID, next.ExpiresAt).Scan(&nextID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RefreshTokenRepoImpl.RotateRefreshToken] error while CreateRefreshToken err", "%v", err.Error())
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, queries.QueryMarkRefreshTokenReplaced, nextID, current.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RefreshTokenRepoImpl.RotateRefreshToken] error while MarkRefreshTokenReplaced err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (r *RefreshTokenRepoImpl) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (err error) {
+	_, err = r.ExecContext(ctx, queries.QueryRevokeRefreshTokenFamily, familyID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RefreshTokenRepoImpl.RevokeRefreshTokenFamily] error while RevokeRefreshTokenFamily err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (r *RefreshTokenRepoImpl) RevokeRefreshToken(ctx context.Context, userID int, tokenHash string) (err error) {
+	_, err = r.ExecContext(ctx, queries.QueryRevokeRefreshTokenByHash, tokenHash, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RefreshTokenRepoImpl.RevokeRefreshToken] error while RevokeRefreshTokenByHash err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (r *RefreshTokenRepoImpl) RevokeUserRefreshTokens(ctx context.Context, userID int) (err error) {
+	_, err = r.ExecContext(ctx, queries.QueryRevokeUserRefreshTokens, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RefreshTokenRepoImpl.RevokeUserRefreshTokens] error while RevokeUserRefreshTokens err", "%v", err.Error())
+		return
+	}
+	return
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"be-shop/internal/app/repo"
+	"be-shop/internal/app/repo/postgres/queries"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+type (
+	RevocationStoreImpl struct {
+		dig.In
+
+		*sql.DB
+	}
+)
+
+func NewRevocationStore(impl RevocationStoreImpl) repo.RevocationStore {
+	return &impl
+}
+
+func (r *RevocationStoreImpl) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) (err error) {
+	_, err = r.ExecContext(ctx, queries.QueryRevokeToken, jti, userID, expiresAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RevocationStoreImpl.RevokeToken] error while RevokeToken err", "%v", err.Error())
+		return
+	}
+
+	// a revoked token only needs to be remembered until it would have expired
+	_, err = r.ExecContext(ctx, queries.QueryDeleteExpiredRevokedTokens)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RevocationStoreImpl.RevokeToken] error while DeleteExpiredRevokedTokens err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (r *RevocationStoreImpl) RevokeUser(ctx context.Context, userID int, before time.Time) (err error) {
+	_, err = r.ExecContext(ctx, queries.QueryRevokeUserTokens, before, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RevocationStoreImpl.RevokeUser] error while RevokeUserTokens err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (r *RevocationStoreImpl) IsRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (revoked bool, err error) {
+	err = r.QueryRowContext(ctx, queries.QueryIsTokenRevoked, jti, userID, issuedAt).Scan(&revoked)
+	if err != nil {
+		slog.ErrorContext(ctx, "[RevocationStoreImpl.IsRevoked] error while IsTokenRevoked err", "%v", err.Error())
+		return
+	}
+	return
+}
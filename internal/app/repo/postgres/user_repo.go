@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/lib/pq"
 	"go.uber.org/dig"
 )
 
@@ -16,6 +17,13 @@ type (
 		CreateUser(ctx context.Context, req models.User) (id int, err error)
 		GetUserByEmail(ctx context.Context, email string) (user models.User, err error)
 		GetUserByID(ctx context.Context, id int) (user models.User, err error)
+		GetUserByIdentity(ctx context.Context, identity string) (user models.User, err error)
+		UpdateUserRole(ctx context.Context, id int, role models.Role) (err error)
+		ResetPassword(ctx context.Context, tokenHash string, password string) (id int, err error)
+		VerifyEmail(ctx context.Context, tokenHash string) (id int, err error)
+		ConfirmEmailChange(ctx context.Context, tokenHash string) (id int, err error)
+		UpdateUsername(ctx context.Context, id int, username string) (err error)
+		UpdatePassword(ctx context.Context, id int, password string) (err error)
 	}
 
 	UserRepoImpl struct {
@@ -30,7 +38,7 @@ func NewUserRepo(impl UserRepoImpl) UserRepo {
 }
 
 func (u *UserRepoImpl) CreateUser(ctx context.Context, req models.User) (id int, err error) {
-	_, err = u.QueryContext(ctx, queries.QueryCreateUser, req.Email, req.Username, req.Password)
+	err = u.QueryRowContext(ctx, queries.QueryCreateUser, req.Email, req.Username, req.Password, req.Role).Scan(&id)
 	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.CreateUser] error while CreateUser err: %v", err.Error()))
 		return id, err
@@ -49,13 +57,116 @@ func (u *UserRepoImpl) GetUserByEmail(ctx context.Context, email string) (user m
 	return
 }
 
+// GetUserByIdentity looks a user up by email or username. It returns
+// ErrUserNotFound when neither matches.
+func (u *UserRepoImpl) GetUserByIdentity(ctx context.Context, identity string) (user models.User, err error) {
+	row := u.QueryRowContext(ctx, queries.QueryGetUserByIdentity, identity)
+	err = row.Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrUserNotFound
+			return
+		}
+		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.GetUserByIdentity] error while GetUserByIdentity err: %v", err.Error()))
+		return
+	}
+	return
+}
+
 func (u *UserRepoImpl) GetUserByID(ctx context.Context, id int) (user models.User, err error) {
 	row := u.QueryRowContext(ctx, queries.QueryGetUserByID, id)
-	err = row.Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	err = row.Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.EmailVerifiedAt)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrUserNotFound
+		}
 		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.GetUserByID] error while GetUserByID err: %v", err.Error()))
 		return user, err
 	}
 
 	return user, nil
 }
+
+func (u *UserRepoImpl) UpdateUserRole(ctx context.Context, id int, role models.Role) (err error) {
+	res, err := u.ExecContext(ctx, queries.QueryUpdateUserRole, role, id)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.UpdateUserRole] error while UpdateUserRole err: %v", err.Error()))
+		return
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		err = ErrUserNotFound
+	}
+	return
+}
+
+func (u *UserRepoImpl) ResetPassword(ctx context.Context, tokenHash string, password string) (id int, err error) {
+	err = u.QueryRowContext(ctx, queries.QueryResetPassword, tokenHash, password).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrUserTokenInvalid
+			return
+		}
+		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.ResetPassword] error while ResetPassword err: %v", err.Error()))
+		return
+	}
+	return
+}
+
+func (u *UserRepoImpl) VerifyEmail(ctx context.Context, tokenHash string) (id int, err error) {
+	err = u.QueryRowContext(ctx, queries.QueryVerifyEmail, tokenHash).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrUserTokenInvalid
+			return
+		}
+		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.VerifyEmail] error while VerifyEmail err: %v", err.Error()))
+		return
+	}
+	return
+}
+
+func (u *UserRepoImpl) ConfirmEmailChange(ctx context.Context, tokenHash string) (id int, err error) {
+	err = u.QueryRowContext(ctx, queries.QueryConfirmEmailChange, tokenHash).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrUserTokenInvalid
+			return
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			err = ErrEmailTaken
+		}
+		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.ConfirmEmailChange] error while ConfirmEmailChange err: %v", err.Error()))
+		return
+	}
+	return
+}
+
+func (u *UserRepoImpl) UpdateUsername(ctx context.Context, id int, username string) (err error) {
+	res, err := u.ExecContext(ctx, queries.QueryUpdateUsername, username, id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			err = ErrUsernameTaken
+		}
+		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.UpdateUsername] error while UpdateUsername err: %v", err.Error()))
+		return
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		err = ErrUserNotFound
+	}
+	return
+}
+
+func (u *UserRepoImpl) UpdatePassword(ctx context.Context, id int, password string) (err error) {
+	res, err := u.ExecContext(ctx, queries.QueryUpdatePassword, password, id)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[UserRepoImpl.UpdatePassword] error while UpdatePassword err: %v", err.Error()))
+		return
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		err = ErrUserNotFound
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres/queries"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+type (
+	UserTokenRepo interface {
+		CreateUserToken(ctx context.Context, req models.UserToken) (id int, err error)
+		// GetLastUserTokenAt returns when the user was last issued a token
+		// for purpose, or nil if never.
+		GetLastUserTokenAt(ctx context.Context, userID int, purpose models.TokenPurpose) (createdAt *time.Time, err error)
+	}
+
+	UserTokenRepoImpl struct {
+		dig.In
+
+		*sql.DB
+	}
+)
+
+func NewUserTokenRepo(impl UserTokenRepoImpl) UserTokenRepo {
+	return &impl
+}
+
+func (u *UserTokenRepoImpl) CreateUserToken(ctx context.Context, req models.UserToken) (id int, err error) {
+	err = u.QueryRowContext(ctx, queries.QueryCreateUserToken, req.UserID, req.Purpose, req.TokenHash, req.ExpiresAt, req.NewEmail).Scan(&id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[UserTokenRepoImpl.CreateUserToken] error while CreateUserToken err", "%v", err.Error())
+		return
+	}
+	return
+}
+
+func (u *UserTokenRepoImpl) GetLastUserTokenAt(ctx context.Context, userID int, purpose models.TokenPurpose) (createdAt *time.Time, err error) {
+	err = u.QueryRowContext(ctx, queries.QueryGetLastUserTokenAt, userID, purpose).Scan(&createdAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "[UserTokenRepoImpl.GetLastUserTokenAt] error while GetLastUserTokenAt err", "%v", err.Error())
+		return
+	}
+	return
+}
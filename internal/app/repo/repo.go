@@ -0,0 +1,41 @@
+// Package repo holds storage interfaces that have more than one
+// implementation. Implementations live in the postgres and memory packages.
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// RevocationStore remembers access tokens that were revoked before they
+	// expired, either one at a time by jti or all tokens a user was issued up
+	// to a cutoff.
+	RevocationStore interface {
+		RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) (err error)
+		RevokeUser(ctx context.Context, userID int, before time.Time) (err error)
+		// IsRevoked reports whether the token jti, issued to userID at
+		// issuedAt, has been revoked.
+		IsRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (revoked bool, err error)
+	}
+
+	// LoginAttemptStore counts failed logins per key, such as a user or an IP
+	// address. Failures are forgotten once a key has had none for the window.
+	LoginAttemptStore interface {
+		// Attempt checks key against limit and, when the attempt may go
+		// ahead, counts it as a failure in the same step, so concurrent
+		// attempts cannot all pass the check before any of them is counted.
+		// A refused attempt is not counted; wait and locked are as returned
+		// by LoginLimit.RetryAfter. An attempt that turns out to succeed is
+		// taken back with Reset or Forgive.
+		Attempt(ctx context.Context, key string, window time.Duration, limit LoginLimit) (wait time.Duration, locked bool, err error)
+		// Forgive takes back one failure counted by Attempt.
+		Forgive(ctx context.Context, key string) (err error)
+		Reset(ctx context.Context, key string) (err error)
+	}
+
+	LoginAttempts struct {
+		Failures    int
+		LastFailure time.Time
+	}
+)
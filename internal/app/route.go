@@ -2,6 +2,7 @@ package app
 
 import (
 	"be-shop/internal/app/controller"
+	"be-shop/internal/app/models"
 	"be-shop/pkg/middleware"
 	"net/http"
 
@@ -15,49 +16,123 @@ func setRoute(
 	productCtrl controller.ProductCtrl,
 	cartCtrl controller.CartCtrl,
 	paymentCtrl controller.PaymentCtrl,
+	orderCtrl controller.OrderCtrl,
+	promotionCtrl controller.PromotionCtrl,
+	addressCtrl controller.AddressCtrl,
 	middleware middleware.MiddleWare,
 ) {
 	e.GET("/", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Hello, World!")
 	})
 
+	e.GET("/.well-known/jwks.json", authCtrl.JWKS)
+
 	base := e.Group("/v1")
 
 	users := base.Group("/users")
 	{
 		users.POST("/register", authCtrl.UserRegistration)
 		users.POST("/login", authCtrl.UserLogin)
+		users.POST("/refresh", authCtrl.RefreshToken)
+		users.GET("/verify", authCtrl.VerifyEmail)
+		users.GET("/email/confirm", authCtrl.ConfirmEmailChange)
+		users.POST("/password/forgot", authCtrl.ForgotPassword)
+		users.POST("/password/reset", authCtrl.ResetPassword)
 	}
 
 	products := base.Group("/products")
 	{
 		products.GET("", productCtrl.GetAllProduct)
-		products.POST("", productCtrl.CreateProduct)
+		products.GET("/search", productCtrl.SearchProducts)
 		products.GET("/:id", productCtrl.GetProductByID)
-		products.PATCH("/:id", productCtrl.UpdateProductPrice)
 		products.GET("/category/:id", productCtrl.GetProductsByCategoryID)
 	}
 
 	categories := base.Group("/categories")
 	{
-		categories.POST("", productCtrl.CreateCategory)
+		categories.GET("", productCtrl.GetCategories)
+	}
+
+	payments := base.Group("/payments")
+	{
+		payments.POST("/webhook/:provider", paymentCtrl.Webhook)
 	}
 
 	base.Use(middleware.AuthUser)
 
+	account := base.Group("/users")
+	{
+		account.POST("/logout", authCtrl.Logout)
+		account.POST("/logout-all", authCtrl.LogoutAll)
+		account.POST("/verify/resend", authCtrl.ResendVerification)
+		account.GET("/me", authCtrl.GetProfile)
+		account.PATCH("/me", authCtrl.UpdateProfile)
+		account.POST("/me/password", authCtrl.ChangePassword)
+		account.POST("/me/email", authCtrl.ChangeEmail)
+		account.GET("/me/addresses", addressCtrl.GetAddresses)
+		account.POST("/me/addresses", addressCtrl.CreateAddress)
+		account.PUT("/me/addresses/:id", addressCtrl.UpdateAddress)
+		account.DELETE("/me/addresses/:id", addressCtrl.DeleteAddress)
+		account.POST("/me/addresses/:id/default", addressCtrl.SetDefaultAddress)
+	}
+
 	cart := base.Group("/cart")
 	{
 		cart.POST("", cartCtrl.AddToCart)
 		cart.GET("", cartCtrl.GetCart)
 		cart.DELETE("", cartCtrl.DeleteAllCart)
+		cart.POST("/coupon", cartCtrl.ApplyCoupon)
+		cart.DELETE("/coupon", cartCtrl.RemoveCoupon)
 		cart.PATCH("/:id", cartCtrl.UpdateCartQuantity)
 		cart.DELETE("/:id", cartCtrl.DeleteCart)
 	}
 
 	orders := base.Group("/orders")
 	{
-		orders.POST("/create", paymentCtrl.Checkout)
-		orders.POST("/simulation", paymentCtrl.SimulatePayment)
+		orders.POST("/create", paymentCtrl.Checkout, middleware.Idempotent)
+		orders.POST("/simulation", paymentCtrl.SimulatePayment, middleware.Idempotent)
+		orders.GET("", orderCtrl.GetOrders)
+		orders.GET("/:order_code", orderCtrl.GetOrderDetail)
+		orders.POST("/:order_code/cancel", orderCtrl.CancelOrder)
+	}
+
+	admin := base.Group("/admin")
+
+	adminProducts := admin.Group("/products", middleware.RequireRole(models.RoleAdmin))
+	{
+		adminProducts.POST("", productCtrl.CreateProduct)
+		adminProducts.GET("/deleted", productCtrl.GetDeletedProducts)
+		adminProducts.DELETE("/:id", productCtrl.DeleteProduct)
+		adminProducts.POST("/:id/restore", productCtrl.RestoreProduct)
+		adminProducts.PATCH("/:id", productCtrl.UpdateProductPrice)
+		adminProducts.PATCH("/:id/stock", productCtrl.AdjustProductStock)
+		adminProducts.GET("/:id/stock/adjustments", productCtrl.GetStockAdjustments)
+	}
+
+	adminCategories := admin.Group("/categories", middleware.RequireRole(models.RoleAdmin))
+	{
+		adminCategories.POST("", productCtrl.CreateCategory)
+		adminCategories.PATCH("/:id", productCtrl.RenameCategory)
+		adminCategories.PUT("/:id/parent", productCtrl.MoveCategory)
+		adminCategories.DELETE("/:id", productCtrl.DeleteCategory)
+	}
+
+	adminPromotions := admin.Group("/promotions", middleware.RequireRole(models.RoleAdmin))
+	{
+		adminPromotions.POST("", promotionCtrl.CreatePromotion)
+		adminPromotions.GET("", promotionCtrl.GetPromotions)
+	}
+
+	adminUsers := admin.Group("/users", middleware.RequireRole(models.RoleAdmin))
+	{
+		adminUsers.PATCH("/:id/role", authCtrl.UpdateUserRole)
+		adminUsers.POST("/:id/unlock", authCtrl.UnlockUser)
+	}
+
+	adminOrders := admin.Group("/orders", middleware.RequireRole(models.RoleAdmin, models.RoleStaff))
+	{
+		adminOrders.GET("", orderCtrl.GetAllOrders)
+		adminOrders.PATCH("/:order_code/status", orderCtrl.UpdateOrderStatus)
 	}
 
 }
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres"
+	"be-shop/pkg/middleware"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go.uber.org/dig"
+)
+
+type (
+	AddressReq struct {
+		Label         string `json:"label" validate:"max=50"`
+		RecipientName string `json:"recipient_name" validate:"required,max=255"`
+		Phone         string `json:"phone" validate:"required,max=30"`
+		Line1         string `json:"line1" validate:"required,max=255"`
+		Line2         string `json:"line2" validate:"max=255"`
+		City          string `json:"city" validate:"required,max=100"`
+		Province      string `json:"province" validate:"required,max=100"`
+		PostalCode    string `json:"postal_code" validate:"required,max=20"`
+		Country       string `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+		IsDefault     bool   `json:"is_default"`
+	}
+
+	AddressSvc interface {
+		CreateAddress(ctx context.Context, req AddressReq) (resp models.DefaultResponse, err error)
+		GetAddresses(ctx context.Context) (resp models.DefaultResponse, err error)
+		UpdateAddress(ctx context.Context, id int, req AddressReq) (resp models.DefaultResponse, err error)
+		DeleteAddress(ctx context.Context, id int) (resp models.DefaultResponse, err error)
+		SetDefaultAddress(ctx context.Context, id int) (resp models.DefaultResponse, err error)
+	}
+
+	AddressSvcImpl struct {
+		dig.In
+
+		AddressRepo postgres.AddressRepo
+	}
+)
+
+const defaultAddressCountry = "ID"
+
+func NewAddressSvc(impl AddressSvcImpl) AddressSvc {
+	return &impl
+}
+
+func (a *AddressSvcImpl) CreateAddress(ctx context.Context, req AddressReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to create address"
+		resp.Code = http.StatusBadGateway
+	}
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[AddressSvc.CreateAddress] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	address, err := a.AddressRepo.CreateAddress(ctx, req.toAddress(userData.UserID, 0))
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressSvc.CreateAddress] error while CreateAddress err", "%v", err.Error())
+		resp.Error = err.Error()
+		return
+	}
+
+	resp.Message = "Address created successfully"
+	resp.Code = http.StatusCreated
+	resp.Data = address
+	return
+}
+
+func (a *AddressSvcImpl) GetAddresses(ctx context.Context) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to get addresses"
+		resp.Code = http.StatusBadGateway
+	}
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[AddressSvc.GetAddresses] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	addresses, err := a.AddressRepo.GetAddresses(ctx, int64(userData.UserID))
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressSvc.GetAddresses] error while GetAddresses err", "%v", err.Error())
+		resp.Error = err.Error()
+		return
+	}
+
+	resp.Message = "Success"
+	resp.Code = http.StatusOK
+	resp.Data = addresses
+	return
+}
+
+func (a *AddressSvcImpl) UpdateAddress(ctx context.Context, id int, req AddressReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to update address"
+		resp.Code = http.StatusBadGateway
+	}
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[AddressSvc.UpdateAddress] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	err = a.AddressRepo.UpdateAddress(ctx, req.toAddress(userData.UserID, id))
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressSvc.UpdateAddress] error while UpdateAddress err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrAddressNotFound) {
+			resp.Message = "Address not found"
+			resp.Code = http.StatusNotFound
+		}
+		resp.Error = err.Error()
+		return
+	}
+
+	address, err := a.AddressRepo.GetAddress(ctx, int64(userData.UserID), id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressSvc.UpdateAddress] error while GetAddress err", "%v", err.Error())
+		resp.Error = err.Error()
+		return
+	}
+
+	resp.Message = "Address updated successfully"
+	resp.Code = http.StatusOK
+	resp.Data = address
+	return
+}
+
+func (a *AddressSvcImpl) DeleteAddress(ctx context.Context, id int) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to delete address"
+		resp.Code = http.StatusBadGateway
+	}
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[AddressSvc.DeleteAddress] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	err = a.AddressRepo.DeleteAddress(ctx, int64(userData.UserID), id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressSvc.DeleteAddress] error while DeleteAddress err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrAddressNotFound) {
+			resp.Message = "Address not found"
+			resp.Code = http.StatusNotFound
+		}
+		resp.Error = err.Error()
+		return
+	}
+
+	resp.Message = "Address deleted successfully"
+	resp.Code = http.StatusOK
+	return
+}
+
+func (a *AddressSvcImpl) SetDefaultAddress(ctx context.Context, id int) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to set default address"
+		resp.Code = http.StatusBadGateway
+	}
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[AddressSvc.SetDefaultAddress] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	err = a.AddressRepo.SetDefaultAddress(ctx, int64(userData.UserID), id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[AddressSvc.SetDefaultAddress] error while SetDefaultAddress err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrAddressNotFound) {
+			resp.Message = "Address not found"
+			resp.Code = http.StatusNotFound
+		}
+		resp.Error = err.Error()
+		return
+	}
+
+	resp.Message = "Default address updated successfully"
+	resp.Code = http.StatusOK
+	return
+}
+
+func (r AddressReq) toAddress(userID, id int) models.Address {
+	country := strings.ToUpper(r.Country)
+	if country == "" {
+		country = defaultAddressCountry
+	}
+	return models.Address{
+		ID:            id,
+		UserID:        userID,
+		Label:         strings.TrimSpace(r.Label),
+		RecipientName: strings.TrimSpace(r.RecipientName),
+		Phone:         strings.TrimSpace(r.Phone),
+		Line1:         strings.TrimSpace(r.Line1),
+		Line2:         strings.TrimSpace(r.Line2),
+		City:          strings.TrimSpace(r.City),
+		Province:      strings.TrimSpace(r.Province),
+		PostalCode:    strings.TrimSpace(r.PostalCode),
+		Country:       country,
+		IsDefault:     r.IsDefault,
+	}
+}
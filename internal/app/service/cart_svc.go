@@ -4,9 +4,13 @@ import (
 	"be-shop/internal/app/models"
 	"be-shop/internal/app/repo/postgres"
 	"be-shop/pkg/middleware"
+	"be-shop/pkg/money"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"go.uber.org/dig"
 )
@@ -20,19 +24,26 @@ type (
 		ProductID int `json:"product_id" validate:"required"`
 		Quantity  int `json:"quantity" validate:"required,gt=0"`
 	}
+
+	ApplyCouponReq struct {
+		Code string `json:"code" validate:"required,max=50"`
+	}
 	CartSvc interface {
 		AddToCart(ctx context.Context, req AddToCartReq) (resp models.DefaultResponse, err error)
 		GetCart(ctx context.Context) (resp models.DefaultResponse, err error)
 		UpdateCartQuantity(ctx context.Context, id int64, req UpdateCartQuantityReq) (resp models.DefaultResponse, err error)
 		DeleteCart(ctx context.Context, id int64) (resp models.DefaultResponse, err error)
 		DeleteAllCart(ctx context.Context) (resp models.DefaultResponse, err error)
+		ApplyCoupon(ctx context.Context, req ApplyCouponReq) (resp models.DefaultResponse, err error)
+		RemoveCoupon(ctx context.Context) (resp models.DefaultResponse, err error)
 	}
 
 	CartSvcImpl struct {
 		dig.In
 
-		CartRepo    postgres.CartRepo
-		ProductRepo postgres.ProductRepo
+		CartRepo      postgres.CartRepo
+		ProductRepo   postgres.ProductRepo
+		PromotionRepo postgres.PromotionRepo
 	}
 )
 
@@ -53,7 +64,7 @@ func (c *CartSvcImpl) AddToCart(ctx context.Context, req AddToCartReq) (resp mod
 		return
 	}
 
-	_, err = c.ProductRepo.GetProductByID(ctx, int64(req.ProductID))
+	product, err := c.ProductRepo.GetProductByID(ctx, int64(req.ProductID))
 	if err != nil {
 		slog.ErrorContext(ctx, "[CartSvcImpl.AddToCart] error while GetProductByID err", "%v", err.Error())
 		resp.Message = "Product not found"
@@ -61,10 +72,31 @@ func (c *CartSvcImpl) AddToCart(ctx context.Context, req AddToCartReq) (resp mod
 		return
 	}
 
+	inCart, err := c.CartRepo.GetCartQuantityByProductID(ctx, int64(userData.UserID), int64(req.ProductID))
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartSvcImpl.AddToCart] error while GetCartQuantityByProductID err", "%v", err.Error())
+		return
+	}
+
+	if inCart+req.Quantity > product.Stock {
+		slog.ErrorContext(ctx, "[CartSvcImpl.AddToCart] error while checking stock", "%v", "insufficient stock")
+		err = fmt.Errorf("insufficient stock")
+		resp.Message = "Insufficient stock"
+		resp.Code = http.StatusConflict
+		resp.Data = models.InsufficientStockItem{
+			ProductID: product.ID,
+			Name:      product.Name,
+			Requested: inCart + req.Quantity,
+			Available: product.Stock,
+		}
+		return
+	}
+
 	entryCart := models.Cart{
-		UserID:    int(userData.UserID),
-		ProductID: req.ProductID,
-		Quantity:  req.Quantity,
+		UserID:       int(userData.UserID),
+		ProductID:    req.ProductID,
+		ProductPrice: &product.Price,
+		Quantity:     req.Quantity,
 	}
 
 	id, err := c.CartRepo.CreateCart(ctx, entryCart)
@@ -108,12 +140,174 @@ func (c *CartSvcImpl) GetCart(ctx context.Context) (resp models.DefaultResponse,
 
 	// should be cached here to avoid multiple request to database
 
+	summary := models.CartSummary{Items: make([]models.Cart, 0, len(carts))}
+	purchasable := make([]models.Cart, 0, len(carts))
+	for _, cart := range carts {
+		var lineTotal money.Money
+		lineTotal, err = cart.ProductPrice.Mul(int64(cart.Quantity))
+		if err != nil {
+			slog.ErrorContext(ctx, "[CartSvcImpl.GetCart] error while line total err", "%v", err.Error())
+			resp.Message = "Cart total is too large"
+			resp.Code = http.StatusUnprocessableEntity
+			return
+		}
+		cart.LineTotal = &lineTotal
+		cart.PriceChanged = cart.PriceAtAdd != nil && !cart.PriceAtAdd.Equal(*cart.ProductPrice)
+		summary.Items = append(summary.Items, cart)
+		if cart.ProductDeleted {
+			continue
+		}
+
+		purchasable = append(purchasable, cart)
+		summary.ItemCount += cart.Quantity
+		summary.Subtotal, err = summary.Subtotal.Add(lineTotal)
+		if err != nil {
+			slog.ErrorContext(ctx, "[CartSvcImpl.GetCart] error while summing subtotal err", "%v", err.Error())
+			resp.Message = "Cart contains products priced in different currencies"
+			resp.Code = http.StatusConflict
+			if errors.Is(err, money.ErrOverflow) {
+				resp.Message = "Cart total is too large"
+				resp.Code = http.StatusUnprocessableEntity
+			}
+			return
+		}
+	}
+	summary.Discount = money.Money{Currency: summary.Subtotal.Currency}
+
+	promotion, err := c.PromotionRepo.GetCartPromotion(ctx, int64(userData.UserID))
+	switch {
+	case errors.Is(err, postgres.ErrPromotionNotFound):
+		err = nil
+	case err != nil:
+		return
+	default:
+		summary.Coupon = &models.AppliedCoupon{Code: promotion.Code, Discount: summary.Discount}
+
+		var discount money.Money
+		discount, err = c.evaluateCoupon(ctx, int64(userData.UserID), promotion, purchasable)
+		switch {
+		case isPromotionError(err):
+			summary.Coupon.Error = err.Error()
+			err = nil
+		case err != nil:
+			slog.ErrorContext(ctx, "[CartSvcImpl.GetCart] error while evaluateCoupon err", "%v", err.Error())
+			return
+		default:
+			summary.Coupon.Discount = discount
+			summary.Discount = discount
+		}
+	}
+
+	summary.Total, err = summary.Subtotal.Sub(summary.Discount)
+	if err != nil {
+		return
+	}
+
 	resp.Message = "Success"
 	resp.Code = http.StatusOK
-	resp.Data = carts
+	resp.Data = summary
 	return
 }
 
+// ApplyCoupon attaches a coupon code to the user's cart after checking it
+// against the cart as it is now. Checkout checks it again.
+func (c *CartSvcImpl) ApplyCoupon(ctx context.Context, req ApplyCouponReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to apply coupon"
+		resp.Code = http.StatusBadGateway
+	}
+
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[CartSvcImpl.ApplyCoupon] error while get user data")
+		resp.Message = "Failed to apply coupon"
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	promotion, err := c.PromotionRepo.GetPromotionByCode(ctx, req.Code)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartSvcImpl.ApplyCoupon] error while GetPromotionByCode err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrPromotionNotFound) {
+			resp.Message = "Coupon not found"
+			resp.Code = http.StatusNotFound
+		}
+		return
+	}
+
+	carts, err := c.CartRepo.GetCartByUserID(ctx, int64(userData.UserID))
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartSvcImpl.ApplyCoupon] error while GetCartByUserID err", "%v", err.Error())
+		return
+	}
+
+	discount, err := c.evaluateCoupon(ctx, int64(userData.UserID), promotion, carts)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartSvcImpl.ApplyCoupon] error while evaluateCoupon err", "%v", err.Error())
+		if isPromotionError(err) {
+			resp.Message = "Coupon cannot be applied"
+			resp.Code = http.StatusUnprocessableEntity
+			resp.Error = err.Error()
+		}
+		return
+	}
+
+	err = c.PromotionRepo.SetCartCoupon(ctx, int64(userData.UserID), promotion.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartSvcImpl.ApplyCoupon] error while SetCartCoupon err", "%v", err.Error())
+		return
+	}
+
+	resp.Message = "Coupon applied successfully"
+	resp.Code = http.StatusOK
+	resp.Data = models.AppliedCoupon{
+		Code:     promotion.Code,
+		Discount: discount,
+	}
+	return
+}
+
+func (c *CartSvcImpl) RemoveCoupon(ctx context.Context) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to remove coupon"
+		resp.Code = http.StatusBadGateway
+	}
+
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[CartSvcImpl.RemoveCoupon] error while get user data")
+		resp.Message = "Failed to remove coupon"
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	err = c.PromotionRepo.DeleteCartCoupon(ctx, int64(userData.UserID))
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartSvcImpl.RemoveCoupon] error while DeleteCartCoupon err", "%v", err.Error())
+		return
+	}
+
+	resp.Message = "Coupon removed successfully"
+	resp.Code = http.StatusOK
+	return
+}
+
+// evaluateCoupon runs every promotion rule against the user and their cart
+// lines and returns the discount the coupon is worth.
+func (c *CartSvcImpl) evaluateCoupon(ctx context.Context, userID int64, promotion models.Promotion, carts []models.Cart) (discount money.Money, err error) {
+	redeemed, err := c.PromotionRepo.CountUserRedemptions(ctx, promotion.ID, userID)
+	if err != nil {
+		return
+	}
+
+	err = promotion.CheckAvailability(time.Now(), redeemed)
+	if err != nil {
+		return
+	}
+
+	return promotion.Discount(carts)
+}
+
 func (c *CartSvcImpl) UpdateCartQuantity(ctx context.Context, id int64, req UpdateCartQuantityReq) (resp models.DefaultResponse, err error) {
 	{
 		resp.Message = "Failed to update cart"
@@ -128,7 +322,41 @@ func (c *CartSvcImpl) UpdateCartQuantity(ctx context.Context, id int64, req Upda
 		return
 	}
 
-	err = c.CartRepo.UpdateCartQuantity(ctx, id, int64(userData.UserID), req.Quantity)
+	productID, err := c.CartRepo.GetCartItemProductID(ctx, int64(userData.UserID), id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartSvcImpl.UpdateCartQuantity] error while GetCartItemProductID err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrCartItemNotFound) {
+			resp.Message = "Cart item not found"
+			resp.Code = http.StatusNotFound
+		}
+		return
+	}
+
+	product, err := c.ProductRepo.GetProductByID(ctx, productID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[CartSvcImpl.UpdateCartQuantity] error while GetProductByID err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrProductNotFound) {
+			resp.Message = "Product not found"
+			resp.Code = http.StatusNotFound
+		}
+		return
+	}
+
+	if req.Quantity > product.Stock {
+		slog.ErrorContext(ctx, "[CartSvcImpl.UpdateCartQuantity] error while checking stock", "%v", "insufficient stock")
+		err = fmt.Errorf("insufficient stock")
+		resp.Message = "Insufficient stock"
+		resp.Code = http.StatusConflict
+		resp.Data = models.InsufficientStockItem{
+			ProductID: product.ID,
+			Name:      product.Name,
+			Requested: req.Quantity,
+			Available: product.Stock,
+		}
+		return
+	}
+
+	err = c.CartRepo.UpdateCartQuantity(ctx, int64(userData.UserID), id, req.Quantity)
 	if err != nil {
 		slog.ErrorContext(ctx, "[CartSvcImpl.UpdateCartQuantity] error while UpdateCartQuantity err", "%v", err.Error())
 		resp.Message = "Failed to update cart"
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrWrongPassword = errors.New("current password is incorrect")
+
+// RetryAfterError is returned when a request is throttled. Controllers send
+// RetryAfter back in the Retry-After header.
+type RetryAfterError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("too many requests, retry after %s", e.RetryAfter.Round(time.Second))
+}
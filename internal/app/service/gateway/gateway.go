@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"be-shop/internal/app/infra"
+	"be-shop/internal/app/models"
+	"be-shop/pkg/money"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	StatusPending  ChargeStatus = "pending"
+	StatusPaid     ChargeStatus = "paid"
+	StatusFailed   ChargeStatus = "failed"
+	StatusExpired  ChargeStatus = "expired"
+	StatusRefunded ChargeStatus = "refunded"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrChargeNotFound   = errors.New("charge not found")
+	ErrUnknownProvider  = errors.New("unknown payment provider")
+	ErrWeakSecret       = errors.New("webhook secret is empty or the sample value")
+)
+
+type (
+	ChargeStatus string
+
+	ChargeReq struct {
+		OrderCode string
+		Amount    money.Money
+		ExpiresAt time.Time
+	}
+
+	Charge struct {
+		Provider   string       `json:"provider"`
+		Reference  string       `json:"reference"`
+		OrderCode  string       `json:"order_code"`
+		Amount     money.Money  `json:"amount"`
+		Status     ChargeStatus `json:"status"`
+		PaymentURL string       `json:"payment_url,omitempty"`
+		ExpiresAt  time.Time    `json:"expires_at"`
+	}
+
+	RefundReq struct {
+		OrderCode string
+		Reference string
+		Amount    money.Money
+		Reason    string
+	}
+
+	RefundResult struct {
+		Reference string
+		Status    ChargeStatus
+	}
+
+	// Notification is a provider callback normalised to the fields we act on.
+	Notification struct {
+		Provider  string       `json:"-"`
+		OrderCode string       `json:"order_code"`
+		Reference string       `json:"reference"`
+		Status    ChargeStatus `json:"status"`
+		Amount    money.Money  `json:"amount"`
+	}
+
+	PaymentGateway interface {
+		Name() string
+		CreateCharge(ctx context.Context, req ChargeReq) (charge Charge, err error)
+		GetStatus(ctx context.Context, orderCode string) (charge Charge, err error)
+		Refund(ctx context.Context, req RefundReq) (result RefundResult, err error)
+		// ParseWebhook authenticates a callback and decodes it. It must
+		// return ErrInvalidSignature when the request cannot be trusted.
+		ParseWebhook(header http.Header, body []byte) (notification Notification, err error)
+	}
+
+	// Registry holds every configured gateway by provider name.
+	Registry struct {
+		gateways    map[string]PaymentGateway
+		defaultName string
+	}
+)
+
+// NewRegistry registers every gateway. It refuses to build one whose webhook
+// secret is missing or public, since the webhook endpoint is unauthenticated
+// and the signature is all that stops a forged "paid" notification.
+func NewRegistry(cfg *infra.PaymentCfg) (*Registry, error) {
+	if cfg.SimulatorSecret == "" || cfg.SimulatorSecret == infra.SampleSimulatorSecret {
+		return nil, fmt.Errorf("%w: PAYMENT_SIMULATOR_SECRET", ErrWeakSecret)
+	}
+
+	r := &Registry{
+		gateways:    make(map[string]PaymentGateway),
+		defaultName: cfg.Provider,
+	}
+	r.Register(NewSimulator(cfg.SimulatorSecret))
+
+	if _, ok := r.gateways[cfg.Provider]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, cfg.Provider)
+	}
+	return r, nil
+}
+
+func (r *Registry) Register(gw PaymentGateway) {
+	r.gateways[gw.Name()] = gw
+}
+
+func (r *Registry) Get(name string) (PaymentGateway, error) {
+	gw, ok := r.gateways[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return gw, nil
+}
+
+func (r *Registry) Default() PaymentGateway {
+	return r.gateways[r.defaultName]
+}
+
+// ForOrder returns the gateway an order was charged through, falling back to
+// the default for orders placed before they carried a provider.
+func (r *Registry) ForOrder(order models.Order) (PaymentGateway, error) {
+	if order.PaymentProvider == "" {
+		return r.Default(), nil
+	}
+	return r.Get(order.PaymentProvider)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body under secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a hex encoded HMAC-SHA256 signature in constant time.
+func VerifySignature(secret string, body []byte, signature string) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || secret == "" {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"be-shop/internal/app/infra"
+	"be-shop/pkg/money"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"order_code":"ORD-1","status":"paid","amount":"10.00"}`)
+	valid := Sign("s3cret", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		wantErr   bool
+	}{
+		{name: "valid", secret: "s3cret", body: body, signature: valid},
+		{name: "tampered body", secret: "s3cret", body: []byte(`{"order_code":"ORD-1","status":"paid","amount":"0.01"}`), signature: valid, wantErr: true},
+		{name: "wrong secret", secret: "other", body: body, signature: valid, wantErr: true},
+		{name: "missing signature", secret: "s3cret", body: body, signature: "", wantErr: true},
+		{name: "not hex", secret: "s3cret", body: body, signature: "zz" + valid[2:], wantErr: true},
+		{name: "truncated", secret: "s3cret", body: body, signature: valid[:len(valid)-2], wantErr: true},
+		{name: "empty secret", secret: "", body: body, signature: Sign("", body), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := VerifySignature(tt.secret, tt.body, tt.signature)
+		if tt.wantErr != errors.Is(err, ErrInvalidSignature) || (!tt.wantErr && err != nil) {
+			t.Errorf("%s: VerifySignature error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSimulatorParseWebhook(t *testing.T) {
+	sim := NewSimulator("s3cret")
+	body := []byte(`{"order_code":"ORD-1","reference":"SIM-1","status":"paid","amount":{"amount":"10.00","currency":"IDR"}}`)
+
+	header := http.Header{}
+	header.Set(SimulatorSignatureHeader, Sign("s3cret", body))
+	notification, err := sim.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	want := Notification{Provider: SimulatorName, OrderCode: "ORD-1", Reference: "SIM-1", Status: StatusPaid, Amount: money.New(1000, "IDR")}
+	if notification != want {
+		t.Errorf("ParseWebhook = %+v, want %+v", notification, want)
+	}
+
+	if _, err := sim.ParseWebhook(http.Header{}, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("missing header error = %v, want ErrInvalidSignature", err)
+	}
+
+	forged := http.Header{}
+	forged.Set(SimulatorSignatureHeader, Sign(infra.SampleSimulatorSecret, body))
+	if _, err := sim.ParseWebhook(forged, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong secret error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestNewRegistryRejectsWeakSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     infra.PaymentCfg
+		wantErr error
+	}{
+		{name: "empty secret", cfg: infra.PaymentCfg{Provider: SimulatorName}, wantErr: ErrWeakSecret},
+		{name: "sample secret", cfg: infra.PaymentCfg{Provider: SimulatorName, SimulatorSecret: infra.SampleSimulatorSecret}, wantErr: ErrWeakSecret},
+		{name: "unknown provider", cfg: infra.PaymentCfg{Provider: "stripe", SimulatorSecret: "s3cret"}, wantErr: ErrUnknownProvider},
+		{name: "configured", cfg: infra.PaymentCfg{Provider: SimulatorName, SimulatorSecret: "s3cret"}},
+	}
+
+	for _, tt := range tests {
+		registry, err := NewRegistry(&tt.cfg)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: NewRegistry error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr == nil && registry.Default().Name() != SimulatorName {
+			t.Errorf("%s: default gateway = %s", tt.name, registry.Default().Name())
+		}
+	}
+}
+
+func TestSimulatorForgetsSettledCharges(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sim := NewSimulator("s3cret")
+	sim.now = func() time.Time { return now }
+	amount := money.New(1000, "IDR")
+
+	for _, code := range []string{"PAID", "REFUNDED", "UNPAID"} {
+		if _, err := sim.CreateCharge(ctx, ChargeReq{OrderCode: code, Amount: amount, ExpiresAt: now.Add(time.Hour)}); err != nil {
+			t.Fatalf("CreateCharge(%s): %v", code, err)
+		}
+	}
+
+	if _, err := sim.Simulate(ctx, "PAID", money.New(1, "IDR")); err == nil {
+		t.Errorf("Simulate with the wrong amount succeeded")
+	}
+	notification, err := sim.Simulate(ctx, "PAID", amount)
+	if err != nil || notification.Status != StatusPaid || notification.Reference == "" {
+		t.Fatalf("Simulate = %+v, %v", notification, err)
+	}
+	if _, err := sim.Refund(ctx, RefundReq{OrderCode: "REFUNDED", Amount: amount}); err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+
+	for _, code := range []string{"PAID", "REFUNDED"} {
+		if _, err := sim.GetStatus(ctx, code); !errors.Is(err, ErrChargeNotFound) {
+			t.Errorf("settled charge %s still kept: %v", code, err)
+		}
+	}
+	if _, err := sim.GetStatus(ctx, "UNPAID"); err != nil {
+		t.Errorf("pending charge forgotten: %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	sim.CreateCharge(ctx, ChargeReq{OrderCode: "NEXT", Amount: amount, ExpiresAt: now.Add(time.Hour)})
+	if _, err := sim.GetStatus(ctx, "UNPAID"); !errors.Is(err, ErrChargeNotFound) {
+		t.Errorf("expired charge still kept: %v", err)
+	}
+	if len(sim.charges) != 1 {
+		t.Errorf("simulator holds %d charges, want 1", len(sim.charges))
+	}
+}
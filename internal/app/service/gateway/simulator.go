@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"be-shop/internal/app/service/utils"
+	"be-shop/pkg/money"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	SimulatorName            = "simulator"
+	SimulatorSignatureHeader = "X-Simulator-Signature"
+)
+
+type (
+	// Simulated is implemented by gateways that let the buyer settle a charge
+	// directly, without a real provider in the loop.
+	Simulated interface {
+		Simulate(ctx context.Context, orderCode string, amount money.Money) (notification Notification, err error)
+	}
+
+	// Simulator is an in-memory gateway for local development. Its webhooks
+	// are signed with the configured secret like a real provider's would be.
+	// It only remembers charges still waiting for payment: settled ones are
+	// forgotten, and unpaid ones once they expire.
+	Simulator struct {
+		secret  string
+		mu      sync.Mutex
+		charges map[string]Charge
+		now     func() time.Time
+	}
+)
+
+func NewSimulator(secret string) *Simulator {
+	return &Simulator{
+		secret:  secret,
+		charges: make(map[string]Charge),
+		now:     time.Now,
+	}
+}
+
+func (s *Simulator) Name() string {
+	return SimulatorName
+}
+
+func (s *Simulator) CreateCharge(_ context.Context, req ChargeReq) (charge Charge, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for orderCode, pending := range s.charges {
+		if !pending.ExpiresAt.IsZero() && pending.ExpiresAt.Before(now) {
+			delete(s.charges, orderCode)
+		}
+	}
+
+	charge = Charge{
+		Provider:   SimulatorName,
+		Reference:  "SIM-" + utils.RandomString(16),
+		OrderCode:  req.OrderCode,
+		Amount:     req.Amount,
+		Status:     StatusPending,
+		PaymentURL: "/v1/orders/simulation",
+		ExpiresAt:  req.ExpiresAt,
+	}
+	s.charges[req.OrderCode] = charge
+	return
+}
+
+func (s *Simulator) GetStatus(_ context.Context, orderCode string) (charge Charge, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	charge, ok := s.charges[orderCode]
+	if !ok {
+		err = ErrChargeNotFound
+	}
+	return
+}
+
+func (s *Simulator) Refund(_ context.Context, req RefundReq) (result RefundResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.charges, req.OrderCode)
+
+	result = RefundResult{
+		Reference: "SIMR-" + utils.RandomString(16),
+		Status:    StatusRefunded,
+	}
+	return
+}
+
+func (s *Simulator) ParseWebhook(header http.Header, body []byte) (notification Notification, err error) {
+	err = VerifySignature(s.secret, body, header.Get(SimulatorSignatureHeader))
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(body, &notification)
+	if err != nil {
+		err = fmt.Errorf("invalid webhook payload: %w", err)
+		return
+	}
+	notification.Provider = SimulatorName
+	return
+}
+
+// Simulate settles the charge for orderCode and returns the notification a
+// provider would have sent for it. Charges the simulator no longer knows,
+// because they were settled, expired or created before a restart, are
+// accepted as-is; the order's own status decides what happens then.
+func (s *Simulator) Simulate(_ context.Context, orderCode string, amount money.Money) (notification Notification, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	charge, ok := s.charges[orderCode]
+	if ok {
+		if !charge.Amount.Equal(amount) {
+			err = fmt.Errorf("amount %v does not match charge amount %v", amount, charge.Amount)
+			return
+		}
+		delete(s.charges, orderCode)
+	}
+
+	notification = Notification{
+		Provider:  SimulatorName,
+		OrderCode: orderCode,
+		Reference: charge.Reference,
+		Status:    StatusPaid,
+		Amount:    amount,
+	}
+	return
+}
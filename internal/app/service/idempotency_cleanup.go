@@ -0,0 +1,102 @@
+package service
+
+import (
+	"be-shop/internal/app/infra"
+	"be-shop/internal/app/repo/postgres"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+type (
+	// IdempotencyCleanupWorker periodically deletes idempotency keys that have
+	// outlived their retention window and can no longer be replayed.
+	IdempotencyCleanupWorker interface {
+		Start()
+		Stop(ctx context.Context) error
+	}
+
+	IdempotencyCleanupWorkerImpl struct {
+		dig.In `ignore-unexported:"true"`
+
+		IdempotencyRepo postgres.IdempotencyRepo
+		IdempotencyCfg  *infra.IdempotencyCfg
+
+		// mu guards cancel and done; Start and Stop may be called from
+		// different goroutines during startup and shutdown.
+		mu     *sync.Mutex
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+)
+
+func NewIdempotencyCleanupWorker(impl IdempotencyCleanupWorkerImpl) IdempotencyCleanupWorker {
+	impl.mu = new(sync.Mutex)
+	return &impl
+}
+
+func (w *IdempotencyCleanupWorkerImpl) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go w.run(ctx, w.done)
+}
+
+func (w *IdempotencyCleanupWorkerImpl) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *IdempotencyCleanupWorkerImpl) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(w.IdempotencyCfg.CleanupInterval)
+	defer ticker.Stop()
+
+	slog.InfoContext(ctx, "[IdempotencyCleanupWorker.run] started", "interval", w.IdempotencyCfg.CleanupInterval.String())
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("[IdempotencyCleanupWorker.run] stopped")
+			return
+		case <-ticker.C:
+			w.deleteExpired(ctx)
+		}
+	}
+}
+
+func (w *IdempotencyCleanupWorkerImpl) deleteExpired(ctx context.Context) {
+	deleted, err := w.IdempotencyRepo.DeleteExpired(ctx, w.IdempotencyCfg.Retention)
+	if err != nil {
+		slog.ErrorContext(ctx, "[IdempotencyCleanupWorker.deleteExpired] error while DeleteExpired err", "%v", err.Error())
+		return
+	}
+	if deleted > 0 {
+		slog.InfoContext(ctx, "[IdempotencyCleanupWorker.deleteExpired] idempotency keys deleted", "count", deleted)
+	}
+}
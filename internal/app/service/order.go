@@ -0,0 +1,417 @@
+package service
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres"
+	"be-shop/internal/app/service/gateway"
+	"be-shop/pkg/middleware"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+const orderDateLayout = "2006-01-02"
+
+type (
+	GetOrdersReq struct {
+		models.PaginationRequest
+		Status string `query:"status" validate:"omitempty,oneof=Pending Paid Processing Shipped Delivered Cancelled Expired RefundPending Refunded"`
+		From   string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+		To     string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+	}
+
+	AdminGetOrdersReq struct {
+		GetOrdersReq
+		UserID int64 `query:"user_id" validate:"omitempty,gt=0"`
+	}
+
+	CancelOrderReq struct {
+		Reason string `json:"reason" validate:"max=200"`
+	}
+
+	// UpdateOrderStatusReq moves a paid order through fulfilment. Payment,
+	// cancellation and refund statuses have their own flows.
+	UpdateOrderStatusReq struct {
+		Status models.OrderStatus `json:"status" validate:"required,oneof=Processing Shipped Delivered"`
+		Note   string             `json:"note" validate:"max=200"`
+	}
+
+	OrderSvc interface {
+		GetOrders(ctx context.Context, req GetOrdersReq) (resp models.DefaultResponse, err error)
+		GetOrderDetail(ctx context.Context, orderCode string) (resp models.DefaultResponse, err error)
+		CancelOrder(ctx context.Context, orderCode string, req CancelOrderReq) (resp models.DefaultResponse, err error)
+		GetAllOrders(ctx context.Context, req AdminGetOrdersReq) (resp models.DefaultResponse, err error)
+		UpdateOrderStatus(ctx context.Context, orderCode string, req UpdateOrderStatusReq) (resp models.DefaultResponse, err error)
+	}
+
+	OrderSvcImpl struct {
+		dig.In
+
+		OrderRepo postgres.OrderRepo
+		Gateways  *gateway.Registry
+	}
+)
+
+func NewOrderSvc(impl OrderSvcImpl) OrderSvc {
+	return &impl
+}
+
+func (o *OrderSvcImpl) GetOrders(ctx context.Context, req GetOrdersReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to get orders"
+		resp.Code = http.StatusBadGateway
+	}
+
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.GetOrders] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	filter := newOrderFilter(req)
+	filter.UserID = int64(userData.UserID)
+
+	data, err := o.listOrders(ctx, req, filter)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.GetOrders] error while listOrders err", "%v", err.Error())
+		if errors.Is(err, models.ErrInvalidCursor) {
+			resp.Message = "Invalid cursor"
+			resp.Code = http.StatusBadRequest
+		}
+		return
+	}
+
+	resp.Message = "Orders fetched successfully"
+	resp.Code = http.StatusOK
+	resp.Data = data
+	return
+}
+
+// GetAllOrders lists orders across all users for staff.
+func (o *OrderSvcImpl) GetAllOrders(ctx context.Context, req AdminGetOrdersReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to get orders"
+		resp.Code = http.StatusBadGateway
+	}
+
+	filter := newOrderFilter(req.GetOrdersReq)
+	filter.UserID = req.UserID
+
+	data, err := o.listOrders(ctx, req.GetOrdersReq, filter)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.GetAllOrders] error while listOrders err", "%v", err.Error())
+		if errors.Is(err, models.ErrInvalidCursor) {
+			resp.Message = "Invalid cursor"
+			resp.Code = http.StatusBadRequest
+		}
+		return
+	}
+
+	resp.Message = "Orders fetched successfully"
+	resp.Code = http.StatusOK
+	resp.Data = data
+	return
+}
+
+// UpdateOrderStatus applies a fulfilment status change made by staff and
+// records who made it.
+func (o *OrderSvcImpl) UpdateOrderStatus(ctx context.Context, orderCode string, req UpdateOrderStatusReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to update order status"
+		resp.Code = http.StatusBadGateway
+		req.Note = strings.TrimSpace(req.Note)
+	}
+
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.UpdateOrderStatus] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	order, err := o.OrderRepo.FindOrderByOrderCode(ctx, orderCode)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.UpdateOrderStatus] error while FindOrderByOrderCode err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrOrderNotFound) {
+			resp.Message = "Order not found"
+			resp.Code = http.StatusNotFound
+		}
+		return
+	}
+
+	if order.Status == req.Status {
+		resp.Message = "Order status unchanged"
+		resp.Code = http.StatusOK
+		resp.Data = orderStatusData(order.OrderCode, order.Status)
+		return
+	}
+
+	transition, err := newOrderTransition(order, req.Status, &userData.UserID, req.Note)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.UpdateOrderStatus] error while newOrderTransition err", "%v", err.Error())
+		resp.Message = "Order status cannot be changed"
+		resp.Code = http.StatusConflict
+		resp.Error = err.Error()
+		return
+	}
+
+	err = o.OrderRepo.UpdateOrderStatus(ctx, transition)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.UpdateOrderStatus] error while UpdateOrderStatus err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrOrderStatusConflict) {
+			resp.Message = "Order status changed, please retry"
+			resp.Code = http.StatusConflict
+		}
+		return
+	}
+
+	resp.Message = "Order status updated successfully"
+	resp.Code = http.StatusOK
+	resp.Data = orderStatusData(order.OrderCode, req.Status)
+	return
+}
+
+func newOrderFilter(req GetOrdersReq) models.OrderFilter {
+	filter := models.OrderFilter{
+		Status: models.OrderStatus(req.Status),
+		Limit:  req.Limit,
+		Offset: (req.Page - 1) * req.Limit,
+	}
+
+	// both bounds are calendar days in local time; "to" is inclusive
+	if req.From != "" {
+		from, _ := time.ParseInLocation(orderDateLayout, req.From, time.Local)
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, _ := time.ParseInLocation(orderDateLayout, req.To, time.Local)
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+	return filter
+}
+
+// listOrders reads one page of the orders matching filter, by cursor when the
+// request asks for one and by page number otherwise.
+func (o *OrderSvcImpl) listOrders(ctx context.Context, req GetOrdersReq, filter models.OrderFilter) (data models.DefaultPaginationResponseData, err error) {
+	if req.UseCursor {
+		var cursor models.Cursor
+		cursor, err = models.DecodeCursor(req.Cursor)
+		if err != nil {
+			return
+		}
+
+		var (
+			orders []models.Order
+			page   models.CursorPage
+		)
+		orders, page, err = o.OrderRepo.GetOrdersByCursor(ctx, filter, cursor)
+		if err != nil {
+			return
+		}
+		data = models.DefaultPaginationResponseData{
+			Results:         orders,
+			DefaultMetaData: models.NewCursorMetaData(req.Limit, page),
+		}
+		return
+	}
+
+	totalItem, orders, err := o.OrderRepo.GetOrders(ctx, filter)
+	if err != nil {
+		return
+	}
+	data = models.DefaultPaginationResponseData{
+		Results:         orders,
+		DefaultMetaData: models.NewPageMetaData(req.Page, req.Limit, totalItem),
+	}
+	return
+}
+
+func (o *OrderSvcImpl) GetOrderDetail(ctx context.Context, orderCode string) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to get order"
+		resp.Code = http.StatusBadGateway
+	}
+
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.GetOrderDetail] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	order, err := o.OrderRepo.GetOrderByOrderCode(ctx, int64(userData.UserID), orderCode)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.GetOrderDetail] error while GetOrderByOrderCode err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrOrderNotFound) {
+			resp.Message = "Order not found"
+			resp.Code = http.StatusNotFound
+		}
+		return
+	}
+
+	resp.Message = "Order fetched successfully"
+	resp.Code = http.StatusOK
+	resp.Data = order
+	return
+}
+
+// CancelOrder cancels a pending order outright, or moves a paid order to
+// RefundPending and records the refund owed. Cancelling an order that is
+// already cancelled or awaiting a refund succeeds without changing anything.
+func (o *OrderSvcImpl) CancelOrder(ctx context.Context, orderCode string, req CancelOrderReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to cancel order"
+		resp.Code = http.StatusBadGateway
+		req.Reason = strings.TrimSpace(req.Reason)
+	}
+
+	userData, ok := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if !ok {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.CancelOrder] error while get user data")
+		resp.Code = http.StatusUnauthorized
+		return
+	}
+
+	order, err := o.OrderRepo.GetOrderByOrderCode(ctx, int64(userData.UserID), orderCode)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.CancelOrder] error while GetOrderByOrderCode err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrOrderNotFound) {
+			resp.Message = "Order not found"
+			resp.Code = http.StatusNotFound
+		}
+		return
+	}
+
+	var (
+		to     models.OrderStatus
+		refund *models.Refund
+	)
+	switch order.Status {
+	case models.OrderStatusCancelled, models.OrderStatusRefundPending, models.OrderStatusRefunded:
+		resp.Message = "Order already cancelled"
+		resp.Code = http.StatusOK
+		resp.Data = orderStatusData(order.OrderCode, order.Status)
+		return
+	case models.OrderStatusPending:
+		to = models.OrderStatusCancelled
+	case models.OrderStatusPaid:
+		to = models.OrderStatusRefundPending
+		refund = &models.Refund{
+			OrderID: order.ID,
+			Amount:  order.TotalAmount,
+			Status:  models.RefundStatusPending,
+			Reason:  req.Reason,
+		}
+	default:
+		err = &InvalidTransitionError{From: order.Status, To: models.OrderStatusCancelled}
+		slog.ErrorContext(ctx, "[OrderSvcImpl.CancelOrder] error while checking status err", "%v", err.Error())
+		resp.Message = "Order can no longer be cancelled"
+		resp.Code = http.StatusConflict
+		resp.Error = err.Error()
+		return
+	}
+
+	transition, err := newOrderTransition(order, to, &userData.UserID, cancelNote(req.Reason))
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.CancelOrder] error while newOrderTransition err", "%v", err.Error())
+		resp.Message = "Order can no longer be cancelled"
+		resp.Code = http.StatusConflict
+		resp.Error = err.Error()
+		return
+	}
+
+	err = o.OrderRepo.CancelOrder(ctx, transition, refund)
+	if errors.Is(err, postgres.ErrOrderStatusConflict) || errors.Is(err, postgres.ErrTxConflict) {
+		// a concurrent request may have cancelled it first; that still
+		// counts as success for the caller
+		order, err = o.OrderRepo.GetOrderByOrderCode(ctx, int64(userData.UserID), orderCode)
+		if err == nil && (order.Status == models.OrderStatusCancelled || order.Status == models.OrderStatusRefundPending) {
+			resp.Message = "Order already cancelled"
+			resp.Code = http.StatusOK
+			resp.Data = orderStatusData(order.OrderCode, order.Status)
+			return
+		}
+		err = postgres.ErrOrderStatusConflict
+		resp.Message = "Order status changed, please retry"
+		resp.Code = http.StatusConflict
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.CancelOrder] error while CancelOrder err", "%v", err.Error())
+		return
+	}
+
+	if refund != nil {
+		to = o.requestRefund(ctx, order, transition, refund)
+	}
+
+	resp.Message = "Order cancelled successfully"
+	resp.Code = http.StatusOK
+	resp.Data = orderStatusData(order.OrderCode, to)
+	return
+}
+
+// requestRefund asks the order's payment provider to return the money and
+// completes the refund straight away when the provider confirms it
+// synchronously. Otherwise the order stays RefundPending until the provider's
+// webhook arrives. It returns the order's resulting status.
+func (o *OrderSvcImpl) requestRefund(ctx context.Context, order models.Order, cancelled models.OrderTransition, refund *models.Refund) models.OrderStatus {
+	gw, err := o.Gateways.ForOrder(order)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.requestRefund] error while ForOrder err", "%v", err.Error())
+		return cancelled.To
+	}
+
+	result, err := gw.Refund(ctx, gateway.RefundReq{
+		OrderCode: order.OrderCode,
+		Reference: order.PaymentReference,
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.requestRefund] error while Refund err", "%v", err.Error())
+		return cancelled.To
+	}
+
+	if result.Status != gateway.StatusRefunded {
+		return cancelled.To
+	}
+
+	order.Status = cancelled.To
+	transition, err := newOrderTransition(order, models.OrderStatusRefunded, nil, "refund confirmed by "+gw.Name())
+	if err != nil {
+		return cancelled.To
+	}
+
+	err = o.OrderRepo.CompleteRefund(ctx, transition, result.Reference)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderSvcImpl.requestRefund] error while CompleteRefund err", "%v", err.Error())
+		return cancelled.To
+	}
+
+	return transition.To
+}
+
+func orderStatusData(orderCode string, status models.OrderStatus) any {
+	return struct {
+		OrderCode string             `json:"order_code"`
+		Status    models.OrderStatus `json:"status"`
+	}{
+		OrderCode: orderCode,
+		Status:    status,
+	}
+}
+
+func cancelNote(reason string) string {
+	if reason == "" {
+		return "cancelled by customer"
+	}
+	return "cancelled by customer: " + reason
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"be-shop/internal/app/infra"
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+type (
+	// OrderExpiryWorker periodically expires unpaid orders whose reservation
+	// window has passed and hands their reserved stock back.
+	OrderExpiryWorker interface {
+		Start()
+		Stop(ctx context.Context) error
+	}
+
+	OrderExpiryWorkerImpl struct {
+		dig.In `ignore-unexported:"true"`
+
+		OrderRepo postgres.OrderRepo
+		OrderCfg  *infra.OrderCfg
+
+		// mu guards cancel and done; Start and Stop may be called from
+		// different goroutines during startup and shutdown.
+		mu     *sync.Mutex
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+)
+
+func NewOrderExpiryWorker(impl OrderExpiryWorkerImpl) OrderExpiryWorker {
+	impl.mu = new(sync.Mutex)
+	return &impl
+}
+
+func (w *OrderExpiryWorkerImpl) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go w.run(ctx, w.done)
+}
+
+func (w *OrderExpiryWorkerImpl) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *OrderExpiryWorkerImpl) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(w.OrderCfg.ExpiryInterval)
+	defer ticker.Stop()
+
+	slog.InfoContext(ctx, "[OrderExpiryWorker.run] started", "interval", w.OrderCfg.ExpiryInterval.String())
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("[OrderExpiryWorker.run] stopped")
+			return
+		case <-ticker.C:
+			w.expireOrders(ctx)
+		}
+	}
+}
+
+func (w *OrderExpiryWorkerImpl) expireOrders(ctx context.Context) {
+	ids, err := w.OrderRepo.GetExpiredOrderIDs(ctx, w.OrderCfg.ExpiryBatchSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "[OrderExpiryWorker.expireOrders] error while GetExpiredOrderIDs err", "%v", err.Error())
+		return
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+
+		transition, _ := newOrderTransition(
+			models.Order{ID: int(id), Status: models.OrderStatusPending},
+			models.OrderStatusExpired, nil, "reservation window elapsed",
+		)
+
+		err = w.OrderRepo.ExpireOrder(ctx, transition)
+		if errors.Is(err, postgres.ErrOrderStatusConflict) {
+			// paid while we were looking at it
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "[OrderExpiryWorker.expireOrders] error while ExpireOrder err", "%v", err.Error())
+			continue
+		}
+		slog.InfoContext(ctx, "[OrderExpiryWorker.expireOrders] order expired", "order_id", id)
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"be-shop/internal/app/models"
+	"fmt"
+)
+
+// orderTransitions lists, for every order status, the statuses it may move
+// to next. Statuses without an entry are terminal.
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusPending:       {models.OrderStatusPaid, models.OrderStatusCancelled, models.OrderStatusExpired},
+	models.OrderStatusPaid:          {models.OrderStatusProcessing, models.OrderStatusRefundPending},
+	models.OrderStatusProcessing:    {models.OrderStatusShipped, models.OrderStatusRefundPending},
+	models.OrderStatusShipped:       {models.OrderStatusDelivered},
+	models.OrderStatusDelivered:     {models.OrderStatusRefundPending},
+	models.OrderStatusRefundPending: {models.OrderStatusRefunded},
+}
+
+// latePaymentTransitions lists the statuses a payment can still arrive for
+// after the order was closed unpaid, or while it is being closed. Such money
+// cannot be kept, so the only way on is RefundPending. These moves are only
+// made for provider payments, never requested by a user, which is why they
+// are kept apart from orderTransitions.
+var latePaymentTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusPending:   {models.OrderStatusRefundPending},
+	models.OrderStatusExpired:   {models.OrderStatusRefundPending},
+	models.OrderStatusCancelled: {models.OrderStatusRefundPending},
+}
+
+type InvalidTransitionError struct {
+	From models.OrderStatus
+	To   models.OrderStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("order cannot move from %s to %s", e.From, e.To)
+}
+
+func ValidateOrderTransition(from, to models.OrderStatus) error {
+	return validateTransition(orderTransitions, from, to)
+}
+
+func validateTransition(transitions map[models.OrderStatus][]models.OrderStatus, from, to models.OrderStatus) error {
+	for _, next := range transitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return &InvalidTransitionError{From: from, To: to}
+}
+
+// newOrderTransition validates moving order to the given status and builds
+// the transition the repositories apply. changedBy is nil for system changes.
+func newOrderTransition(order models.Order, to models.OrderStatus, changedBy *int, note string) (transition models.OrderTransition, err error) {
+	return buildOrderTransition(orderTransitions, order, to, changedBy, note)
+}
+
+// newLatePaymentTransition validates and builds the move of an order that was
+// paid after it closed into RefundPending, see latePaymentTransitions.
+func newLatePaymentTransition(order models.Order, changedBy *int, note string) (transition models.OrderTransition, err error) {
+	return buildOrderTransition(latePaymentTransitions, order, models.OrderStatusRefundPending, changedBy, note)
+}
+
+func buildOrderTransition(transitions map[models.OrderStatus][]models.OrderStatus, order models.Order, to models.OrderStatus, changedBy *int, note string) (transition models.OrderTransition, err error) {
+	err = validateTransition(transitions, order.Status, to)
+	if err != nil {
+		return
+	}
+
+	transition = models.OrderTransition{
+		OrderID:   int64(order.ID),
+		From:      order.Status,
+		To:        to,
+		ChangedBy: changedBy,
+		Note:      note,
+	}
+	return
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"be-shop/internal/app/models"
+	"errors"
+	"testing"
+)
+
+var allOrderStatuses = []models.OrderStatus{
+	models.OrderStatusPending,
+	models.OrderStatusPaid,
+	models.OrderStatusProcessing,
+	models.OrderStatusShipped,
+	models.OrderStatusDelivered,
+	models.OrderStatusCancelled,
+	models.OrderStatusExpired,
+	models.OrderStatusRefundPending,
+	models.OrderStatusRefunded,
+}
+
+type statusEdge struct {
+	from, to models.OrderStatus
+}
+
+// TestValidateOrderTransition checks every pair of statuses, so an edge added
+// to or dropped from orderTransitions has to be made here as well.
+func TestValidateOrderTransition(t *testing.T) {
+	allowed := map[statusEdge]bool{
+		{models.OrderStatusPending, models.OrderStatusPaid}:             true,
+		{models.OrderStatusPending, models.OrderStatusCancelled}:        true,
+		{models.OrderStatusPending, models.OrderStatusExpired}:          true,
+		{models.OrderStatusPaid, models.OrderStatusProcessing}:          true,
+		{models.OrderStatusPaid, models.OrderStatusRefundPending}:       true,
+		{models.OrderStatusProcessing, models.OrderStatusShipped}:       true,
+		{models.OrderStatusProcessing, models.OrderStatusRefundPending}: true,
+		{models.OrderStatusShipped, models.OrderStatusDelivered}:        true,
+		{models.OrderStatusDelivered, models.OrderStatusRefundPending}:  true,
+		{models.OrderStatusRefundPending, models.OrderStatusRefunded}:   true,
+	}
+
+	for _, from := range allOrderStatuses {
+		for _, to := range allOrderStatuses {
+			err := ValidateOrderTransition(from, to)
+			if allowed[statusEdge{from, to}] {
+				if err != nil {
+					t.Errorf("%s -> %s rejected: %v", from, to, err)
+				}
+				continue
+			}
+
+			var transitionErr *InvalidTransitionError
+			if !errors.As(err, &transitionErr) || transitionErr.From != from || transitionErr.To != to {
+				t.Errorf("%s -> %s error = %v, want InvalidTransitionError", from, to, err)
+			}
+		}
+	}
+}
+
+func TestTerminalStatusesHaveNoWayOut(t *testing.T) {
+	for _, from := range []models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusExpired, models.OrderStatusRefunded} {
+		if next := orderTransitions[from]; len(next) != 0 {
+			t.Errorf("terminal status %s may move to %v", from, next)
+		}
+	}
+}
+
+func TestNewOrderTransition(t *testing.T) {
+	admin := 7
+	order := models.Order{ID: 42, Status: models.OrderStatusPaid}
+
+	transition, err := newOrderTransition(order, models.OrderStatusProcessing, &admin, "packing")
+	if err != nil {
+		t.Fatalf("newOrderTransition: %v", err)
+	}
+	if transition.OrderID != 42 || transition.From != models.OrderStatusPaid || transition.To != models.OrderStatusProcessing ||
+		transition.ChangedBy != &admin || transition.Note != "packing" {
+		t.Errorf("newOrderTransition = %+v", transition)
+	}
+
+	tests := []struct {
+		from models.OrderStatus
+		to   models.OrderStatus
+	}{
+		{from: models.OrderStatusPending, to: models.OrderStatusShipped},
+		{from: models.OrderStatusPaid, to: models.OrderStatusPending},
+		{from: models.OrderStatusDelivered, to: models.OrderStatusCancelled},
+		{from: models.OrderStatusCancelled, to: models.OrderStatusPaid},
+		{from: models.OrderStatusCancelled, to: models.OrderStatusRefundPending},
+		{from: models.OrderStatusExpired, to: models.OrderStatusPaid},
+		{from: models.OrderStatusExpired, to: models.OrderStatusRefundPending},
+		{from: models.OrderStatusRefunded, to: models.OrderStatusRefundPending},
+	}
+	for _, tt := range tests {
+		transition, err := newOrderTransition(models.Order{ID: 1, Status: tt.from}, tt.to, nil, "")
+		var transitionErr *InvalidTransitionError
+		if !errors.As(err, &transitionErr) {
+			t.Errorf("%s -> %s = %+v, %v, want InvalidTransitionError", tt.from, tt.to, transition, err)
+		}
+	}
+}
+
+func TestNewLatePaymentTransition(t *testing.T) {
+	for _, from := range allOrderStatuses {
+		transition, err := newLatePaymentTransition(models.Order{ID: 1, Status: from}, nil, "late")
+		switch from {
+		case models.OrderStatusPending, models.OrderStatusExpired, models.OrderStatusCancelled:
+			if err != nil || transition.From != from || transition.To != models.OrderStatusRefundPending {
+				t.Errorf("late payment from %s = %+v, %v, want move to RefundPending", from, transition, err)
+			}
+		default:
+			var transitionErr *InvalidTransitionError
+			if !errors.As(err, &transitionErr) {
+				t.Errorf("late payment from %s error = %v, want InvalidTransitionError", from, err)
+			}
+		}
+	}
+}
@@ -1,33 +1,51 @@
 package service
 
 import (
+	"be-shop/internal/app/infra"
 	"be-shop/internal/app/models"
 	"be-shop/internal/app/repo/postgres"
+	"be-shop/internal/app/service/gateway"
 	"be-shop/internal/app/service/utils"
 	"be-shop/pkg/middleware"
+	"be-shop/pkg/money"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"go.uber.org/dig"
 )
 
 type (
+	// CheckoutReq picks the shipping address for the order. Without an
+	// AddressID the user's default address is used.
+	CheckoutReq struct {
+		AddressID int `json:"address_id" validate:"omitempty,gt=0"`
+	}
+
 	SimulationPaymentReq struct {
-		OrderCode string  `json:"order_code" validate:"required"`
-		Amount    float64 `json:"amount" validate:"required"`
+		OrderCode string      `json:"order_code" validate:"required"`
+		Amount    money.Money `json:"amount" validate:"required,gt=0"`
 	}
 
 	PaymentSvc interface {
-		CreatePayment(ctx context.Context) (resp models.DefaultResponse, err error)
+		CreatePayment(ctx context.Context, req CheckoutReq) (resp models.DefaultResponse, err error)
 		SimulationPayment(ctx context.Context, req SimulationPaymentReq) (resp models.DefaultResponse, err error)
+		HandleWebhook(ctx context.Context, provider string, header http.Header, body []byte) (resp models.DefaultResponse, err error)
 	}
 
 	PaymentSvcImpl struct {
 		dig.In
 
 		PaymentRepo postgres.PaymentRepo
+		OrderRepo   postgres.OrderRepo
+		AddressRepo postgres.AddressRepo
+		OrderCfg    *infra.OrderCfg
+		AuthCfg     *infra.AuthCfg
+		Gateways    *gateway.Registry
 	}
 )
 
@@ -35,7 +53,7 @@ func NewPaymentSvc(impl PaymentSvcImpl) PaymentSvc {
 	return &impl
 }
 
-func (p *PaymentSvcImpl) CreatePayment(ctx context.Context) (resp models.DefaultResponse, err error) {
+func (p *PaymentSvcImpl) CreatePayment(ctx context.Context, req CheckoutReq) (resp models.DefaultResponse, err error) {
 	{
 		resp.Message = "Failed to create payment"
 		resp.Code = http.StatusBadGateway
@@ -47,27 +65,131 @@ func (p *PaymentSvcImpl) CreatePayment(ctx context.Context) (resp models.Default
 		resp.Code = http.StatusUnauthorized
 		return
 	}
+	if p.AuthCfg.RequireVerifiedEmailForCheckout && !userData.EmailVerified {
+		resp.Message = "Please verify your email address before checking out"
+		resp.Code = http.StatusForbidden
+		return
+	}
+
+	var address models.Address
+	if req.AddressID != 0 {
+		address, err = p.AddressRepo.GetAddress(ctx, int64(userData.UserID), req.AddressID)
+	} else {
+		address, err = p.AddressRepo.GetDefaultAddress(ctx, int64(userData.UserID))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.CreatePayment] error while get shipping address err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrAddressNotFound) {
+			resp.Message = "Address not found"
+			resp.Code = http.StatusNotFound
+			if req.AddressID == 0 {
+				resp.Message = "Please add a shipping address before checking out"
+				resp.Code = http.StatusUnprocessableEntity
+			}
+		}
+		resp.Error = err.Error()
+		return
+	}
+
 	orderCode := utils.GenerateOrderCode(strings.Split(userData.Email, "@")[0])
-	total, err := p.PaymentRepo.Checkout(ctx, int64(userData.UserID), orderCode)
+	expiresAt := time.Now().Add(p.OrderCfg.ReservationWindow)
+	order, err := p.PaymentRepo.Checkout(ctx, int64(userData.UserID), orderCode, expiresAt, address.Snapshot())
 	if err != nil {
 		slog.ErrorContext(ctx, "[PaymentSvc.CreatePayment] error while Checkout err", "%v", err.Error())
+		var stockErr *postgres.InsufficientStockError
+		if errors.As(err, &stockErr) {
+			resp.Message = "Insufficient stock"
+			resp.Code = http.StatusConflict
+			resp.Data = stockErr.Items
+		}
+		var unavailableErr *postgres.UnavailableProductError
+		if errors.As(err, &unavailableErr) {
+			resp.Message = "Some products in the cart are no longer available"
+			resp.Code = http.StatusConflict
+			resp.Data = unavailableErr.Items
+		}
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			resp.Message = "Cart contains products priced in different currencies"
+			resp.Code = http.StatusConflict
+		}
+		if errors.Is(err, money.ErrOverflow) {
+			resp.Message = "Cart total is too large"
+			resp.Code = http.StatusUnprocessableEntity
+		}
+		if isPromotionError(err) {
+			resp.Message = "Coupon cannot be applied"
+			resp.Code = http.StatusUnprocessableEntity
+		}
+		if errors.Is(err, postgres.ErrTxConflict) {
+			resp.Message = "Checkout conflicted with another request, please retry"
+			resp.Code = http.StatusConflict
+		}
+		resp.Error = err.Error()
+		return
+	}
+
+	// the order is already placed; if it cannot be charged it is cancelled
+	// and the cart handed back, so the client can simply check out again
+	gw := p.Gateways.Default()
+	charge, err := gw.CreateCharge(ctx, gateway.ChargeReq{
+		OrderCode: orderCode,
+		Amount:    order.TotalAmount,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.CreatePayment] error while CreateCharge err", "%v", err.Error())
+		p.revertCheckout(ctx, order)
+		resp.Message = "Failed to reach payment provider"
 		resp.Error = err.Error()
 		return
 	}
 
+	err = p.PaymentRepo.SetPaymentReference(ctx, orderCode, charge.Provider, charge.Reference)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.CreatePayment] error while SetPaymentReference err", "%v", err.Error())
+		p.revertCheckout(ctx, order)
+		return
+	}
+
 	resp.Message = "Payment created successfully"
 	resp.Code = http.StatusCreated
 	resp.Data = struct {
-		OrderCode   string  `json:"order_code"`
-		TotalAmount float64 `json:"total_amount"`
+		OrderCode       string         `json:"order_code"`
+		SubtotalAmount  money.Money    `json:"subtotal_amount"`
+		DiscountAmount  money.Money    `json:"discount_amount"`
+		TotalAmount     money.Money    `json:"total_amount"`
+		ExpiresAt       time.Time      `json:"expires_at"`
+		Payment         gateway.Charge `json:"payment"`
+		ShippingAddress models.Address `json:"shipping_address"`
 	}{
-		OrderCode:   orderCode,
-		TotalAmount: total,
+		OrderCode:       orderCode,
+		SubtotalAmount:  order.SubtotalAmount,
+		DiscountAmount:  order.DiscountAmount,
+		TotalAmount:     order.TotalAmount,
+		ExpiresAt:       expiresAt,
+		Payment:         charge,
+		ShippingAddress: *order.ShippingAddress,
 	}
 
 	return
 }
 
+// revertCheckout cancels an order whose charge could not be set up and
+// returns its lines to the cart. A failure here is only logged: the order
+// then expires with its reservation as any unpaid order would.
+func (p *PaymentSvcImpl) revertCheckout(ctx context.Context, order models.Order) {
+	transition, err := newOrderTransition(order, models.OrderStatusCancelled, nil, "payment provider unavailable")
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.revertCheckout] error while newOrderTransition err", "%v", err.Error())
+		return
+	}
+
+	err = p.PaymentRepo.RevertCheckout(ctx, transition)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.revertCheckout] error while RevertCheckout err", "%v", err.Error())
+	}
+}
+
 func (p *PaymentSvcImpl) SimulationPayment(ctx context.Context, req SimulationPaymentReq) (resp models.DefaultResponse, err error) {
 	{
 		resp.Message = "Failed to simulate payment"
@@ -87,26 +209,249 @@ func (p *PaymentSvcImpl) SimulationPayment(ctx context.Context, req SimulationPa
 		return
 	}
 
-	if payment.Status == "Settlement" {
+	if payment.Status == models.OrderStatusPaid {
 		resp.Message = "Payment already settled"
 		resp.Code = http.StatusBadRequest
 		return
 	}
 
-	if req.Amount != payment.TotalAmount {
+	// a simulated payment moves no money, so unlike a provider webhook it is
+	// refused outright once the order is closed or its window has passed
+	if payment.Status == models.OrderStatusExpired || (payment.ExpiresAt != nil && !time.Now().Before(*payment.ExpiresAt)) {
+		resp.Message = "Order has expired"
+		resp.Code = http.StatusGone
+		return
+	}
+
+	if payment.Status == models.OrderStatusCancelled {
+		resp.Message = "Order has been cancelled"
+		resp.Code = http.StatusConflict
+		return
+	}
+
+	amount := orderAmount(req.Amount, payment)
+	if !amount.Equal(payment.TotalAmount) {
+		resp.Message = "Invalid amount"
+		resp.Code = http.StatusBadRequest
+		return
+	}
+
+	gw, err := p.Gateways.ForOrder(payment)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.SimulationPayment] error while ForOrder err", "%v", err.Error())
+		return
+	}
+
+	simulator, ok := gw.(gateway.Simulated)
+	if !ok {
+		resp.Message = "Payment simulation is not available"
+		resp.Code = http.StatusNotFound
+		return
+	}
+
+	notification, err := simulator.Simulate(ctx, req.OrderCode, amount)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.SimulationPayment] error while Simulate err", "%v", err.Error())
 		resp.Message = "Invalid amount"
 		resp.Code = http.StatusBadRequest
 		return
 	}
 
-	err = p.PaymentRepo.UpdatePaymentStatus(ctx, int64(userData.UserID), req.OrderCode, "Settlement")
+	resp, err = p.applyNotification(ctx, payment, notification, &userData.UserID)
 	if err != nil {
-		slog.ErrorContext(ctx, "[PaymentSvc.SimulationPayment] error while UpdatePaymentStatus err", "%v", err.Error())
+		slog.ErrorContext(ctx, "[PaymentSvc.SimulationPayment] error while applyNotification err", "%v", err.Error())
 		return
 	}
 
 	resp.Message = "Payment simulated successfully"
+	return
+}
+
+// HandleWebhook authenticates a provider callback and applies it to the order
+// it names. Replayed notifications are acknowledged without side effects.
+func (p *PaymentSvcImpl) HandleWebhook(ctx context.Context, provider string, header http.Header, body []byte) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to process notification"
+		resp.Code = http.StatusBadGateway
+	}
+
+	gw, err := p.Gateways.Get(provider)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.HandleWebhook] error while Get gateway err", "%v", err.Error())
+		resp.Message = "Unknown payment provider"
+		resp.Code = http.StatusNotFound
+		return
+	}
+
+	notification, err := gw.ParseWebhook(header, body)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.HandleWebhook] error while ParseWebhook err", "%v", err.Error())
+		resp.Message = "Invalid notification"
+		resp.Code = http.StatusBadRequest
+		if errors.Is(err, gateway.ErrInvalidSignature) {
+			resp.Message = "Invalid signature"
+			resp.Code = http.StatusUnauthorized
+		}
+		return
+	}
+
+	order, err := p.PaymentRepo.FindPaymentByOrderCode(ctx, notification.OrderCode)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.HandleWebhook] error while FindPaymentByOrderCode err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrOrderNotFound) {
+			resp.Message = "Order not found"
+			resp.Code = http.StatusNotFound
+		}
+		return
+	}
+
+	if order.PaymentProvider != "" && order.PaymentProvider != notification.Provider {
+		err = fmt.Errorf("order %s is not paid through %s", order.OrderCode, notification.Provider)
+		slog.ErrorContext(ctx, "[PaymentSvc.HandleWebhook] error while matching provider err", "%v", err.Error())
+		resp.Message = "Order not found"
+		resp.Code = http.StatusNotFound
+		return
+	}
+
+	return p.applyNotification(ctx, order, notification, nil)
+}
+
+// orderAmount reads an amount sent without a currency as being in the
+// currency of the order it pays for.
+func orderAmount(amount money.Money, order models.Order) money.Money {
+	if amount.Currency == "" {
+		amount.Currency = order.TotalAmount.Currency
+	}
+	return amount
+}
+
+// applyNotification moves order to the status a provider notification
+// reports. Notifications for a status the order already has, and failures
+// reported for an order that is no longer waiting for payment, are no-ops.
+// A payment for an order that was closed in the meantime is still recorded,
+// together with the refund it is owed.
+func (p *PaymentSvcImpl) applyNotification(ctx context.Context, order models.Order, notification gateway.Notification, changedBy *int) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to process notification"
+		resp.Code = http.StatusBadGateway
+	}
+
+	var to models.OrderStatus
+	switch notification.Status {
+	case gateway.StatusPaid:
+		to = models.OrderStatusPaid
+	case gateway.StatusFailed, gateway.StatusExpired:
+		to = models.OrderStatusCancelled
+	case gateway.StatusRefunded:
+		to = models.OrderStatusRefunded
+	default:
+		resp.Message = "Notification acknowledged"
+		resp.Code = http.StatusOK
+		return
+	}
+
+	if order.Status == to || isSettledNotification(order.Status, to) {
+		resp.Message = "Notification already processed"
+		resp.Code = http.StatusOK
+		resp.Data = orderStatusData(order.OrderCode, order.Status)
+		return
+	}
+
+	if to == models.OrderStatusPaid && !orderAmount(notification.Amount, order).Equal(order.TotalAmount) {
+		err = fmt.Errorf("paid amount %v does not match order total %v", notification.Amount, order.TotalAmount)
+		resp.Message = "Invalid amount"
+		resp.Code = http.StatusUnprocessableEntity
+		resp.Error = err.Error()
+		return
+	}
+
+	if to == models.OrderStatusPaid && (order.Status == models.OrderStatusExpired || order.Status == models.OrderStatusCancelled) {
+		return p.recordLatePayment(ctx, order, notification, changedBy)
+	}
+
+	transition, err := newOrderTransition(order, to, changedBy, "payment "+string(notification.Status)+" via "+notification.Provider)
+	if err != nil {
+		resp.Message = "Order cannot be updated"
+		resp.Code = http.StatusConflict
+		resp.Error = err.Error()
+		return
+	}
+
+	switch to {
+	case models.OrderStatusPaid:
+		err = p.PaymentRepo.SettlePayment(ctx, transition)
+		if errors.Is(err, postgres.ErrOrderExpired) {
+			return p.recordLatePayment(ctx, order, notification, changedBy)
+		}
+	case models.OrderStatusCancelled:
+		err = p.OrderRepo.CancelOrder(ctx, transition, nil)
+	case models.OrderStatusRefunded:
+		err = p.OrderRepo.CompleteRefund(ctx, transition, notification.Reference)
+	}
+	if err != nil {
+		if errors.Is(err, postgres.ErrOrderStatusConflict) || errors.Is(err, postgres.ErrTxConflict) {
+			resp.Message = "Order status changed, please retry"
+			resp.Code = http.StatusConflict
+		}
+		return
+	}
+
+	resp.Message = "Order updated successfully"
 	resp.Code = http.StatusOK
+	resp.Data = orderStatusData(order.OrderCode, to)
+	return
+}
+
+// isSettledNotification reports whether a notification moving an order to
+// the given status has nothing left to do: a failure reported once the order
+// is no longer Pending, or a payment for an order that is already past Paid.
+func isSettledNotification(status, to models.OrderStatus) bool {
+	switch to {
+	case models.OrderStatusCancelled:
+		return status != models.OrderStatusPending
+	case models.OrderStatusPaid:
+		return status != models.OrderStatusPending && status != models.OrderStatusExpired && status != models.OrderStatusCancelled
+	}
+	return false
+}
 
+// recordLatePayment books a payment the provider captured after the order was
+// closed unpaid. The money cannot be kept, so the order moves to
+// RefundPending with a refund for the full amount, and the provider gets a
+// success response instead of retrying the notification forever.
+func (p *PaymentSvcImpl) recordLatePayment(ctx context.Context, order models.Order, notification gateway.Notification, changedBy *int) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to process notification"
+		resp.Code = http.StatusBadGateway
+	}
+
+	transition, err := newLatePaymentTransition(order, changedBy, "payment received via "+notification.Provider+" after the order was closed")
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.recordLatePayment] error while newLatePaymentTransition err", "%v", err.Error())
+		resp.Message = "Order cannot be updated"
+		resp.Code = http.StatusConflict
+		resp.Error = err.Error()
+		return
+	}
+	refund := models.Refund{
+		OrderID: order.ID,
+		Amount:  order.TotalAmount,
+		Status:  models.RefundStatusPending,
+		Reason:  "payment received after the order was closed",
+	}
+
+	err = p.PaymentRepo.RecordLatePayment(ctx, transition, notification.Provider, notification.Reference, refund)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PaymentSvc.recordLatePayment] error while RecordLatePayment err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrOrderStatusConflict) || errors.Is(err, postgres.ErrTxConflict) {
+			resp.Message = "Order status changed, please retry"
+			resp.Code = http.StatusConflict
+		}
+		return
+	}
+
+	resp.Message = "Payment received after the order was closed, a refund is pending"
+	resp.Code = http.StatusOK
+	resp.Data = orderStatusData(order.OrderCode, transition.To)
 	return
 }
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"be-shop/internal/app/infra"
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres"
+	"be-shop/internal/app/service/gateway"
+	"be-shop/pkg/money"
+	"context"
+	"net/http"
+	"testing"
+)
+
+// fakePaymentRepo serves one order and records what the service asked of it.
+// Methods the webhook path does not use are left to the embedded nil
+// interface.
+type fakePaymentRepo struct {
+	postgres.PaymentRepo
+
+	order     models.Order
+	settleErr error
+	settled   []models.OrderTransition
+	late      []models.OrderTransition
+	refunds   []models.Refund
+}
+
+func (f *fakePaymentRepo) FindPaymentByOrderCode(ctx context.Context, orderCode string) (models.Order, error) {
+	if orderCode != f.order.OrderCode {
+		return models.Order{}, postgres.ErrOrderNotFound
+	}
+	return f.order, nil
+}
+
+func (f *fakePaymentRepo) SettlePayment(ctx context.Context, transition models.OrderTransition) error {
+	f.settled = append(f.settled, transition)
+	return f.settleErr
+}
+
+func (f *fakePaymentRepo) RecordLatePayment(ctx context.Context, transition models.OrderTransition, provider, reference string, refund models.Refund) error {
+	f.late = append(f.late, transition)
+	f.refunds = append(f.refunds, refund)
+	return nil
+}
+
+const testWebhookSecret = "test-webhook-secret"
+
+func newWebhookTestSvc(t *testing.T, repo *fakePaymentRepo) *PaymentSvcImpl {
+	registry, err := gateway.NewRegistry(&infra.PaymentCfg{Provider: gateway.SimulatorName, SimulatorSecret: testWebhookSecret})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	return &PaymentSvcImpl{PaymentRepo: repo, Gateways: registry}
+}
+
+func signedWebhook(secret string, body string) http.Header {
+	header := http.Header{}
+	header.Set(gateway.SimulatorSignatureHeader, gateway.Sign(secret, []byte(body)))
+	return header
+}
+
+func TestHandleWebhookLatePayment(t *testing.T) {
+	const paid = `{"order_code":"ORD-1","reference":"SIM-1","status":"paid","amount":{"amount":"150.00","currency":"IDR"}}`
+	total := money.New(15000, "IDR")
+
+	tests := []struct {
+		name        string
+		status      models.OrderStatus
+		settleErr   error
+		wantCode    int
+		wantSettled bool
+		wantLate    bool
+	}{
+		{name: "pending is settled", status: models.OrderStatusPending, wantCode: http.StatusOK, wantSettled: true},
+		{name: "expired is refunded", status: models.OrderStatusExpired, wantCode: http.StatusOK, wantLate: true},
+		{name: "cancelled is refunded", status: models.OrderStatusCancelled, wantCode: http.StatusOK, wantLate: true},
+		{name: "pending past its window is refunded", status: models.OrderStatusPending, settleErr: postgres.ErrOrderExpired, wantCode: http.StatusOK, wantSettled: true, wantLate: true},
+		{name: "serialization conflict is retryable", status: models.OrderStatusPending, settleErr: postgres.ErrTxConflict, wantCode: http.StatusConflict, wantSettled: true},
+		{name: "already paid is a no-op", status: models.OrderStatusPaid, wantCode: http.StatusOK},
+		{name: "already refunded is a no-op", status: models.OrderStatusRefunded, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		repo := &fakePaymentRepo{
+			order:     models.Order{ID: 1, OrderCode: "ORD-1", Status: tt.status, TotalAmount: total},
+			settleErr: tt.settleErr,
+		}
+		svc := newWebhookTestSvc(t, repo)
+
+		resp, _ := svc.HandleWebhook(context.Background(), gateway.SimulatorName, signedWebhook(testWebhookSecret, paid), []byte(paid))
+		if resp.Code != tt.wantCode {
+			t.Errorf("%s: code = %d (%s), want %d", tt.name, resp.Code, resp.Message, tt.wantCode)
+		}
+		if (len(repo.settled) > 0) != tt.wantSettled {
+			t.Errorf("%s: SettlePayment calls = %d, want called %v", tt.name, len(repo.settled), tt.wantSettled)
+		}
+		if (len(repo.late) > 0) != tt.wantLate {
+			t.Errorf("%s: RecordLatePayment calls = %d, want called %v", tt.name, len(repo.late), tt.wantLate)
+			continue
+		}
+		if !tt.wantLate {
+			continue
+		}
+
+		transition := repo.late[0]
+		if transition.From != tt.status || transition.To != models.OrderStatusRefundPending || transition.OrderID != 1 {
+			t.Errorf("%s: late transition = %+v, want %s -> %s", tt.name, transition, tt.status, models.OrderStatusRefundPending)
+		}
+		if refund := repo.refunds[0]; !refund.Amount.Equal(total) || refund.Status != models.RefundStatusPending {
+			t.Errorf("%s: refund = %+v, want pending refund of %v", tt.name, refund, total)
+		}
+	}
+}
+
+func TestHandleWebhookRejectsBadSignature(t *testing.T) {
+	const body = `{"order_code":"ORD-1","status":"paid","amount":"150.00"}`
+
+	tests := []struct {
+		name   string
+		header http.Header
+	}{
+		{name: "missing header", header: http.Header{}},
+		{name: "wrong secret", header: signedWebhook(infra.SampleSimulatorSecret, body)},
+		{name: "tampered body", header: signedWebhook(testWebhookSecret, `{"order_code":"ORD-1","status":"paid","amount":"1.00"}`)},
+	}
+
+	for _, tt := range tests {
+		repo := &fakePaymentRepo{order: models.Order{ID: 1, OrderCode: "ORD-1", Status: models.OrderStatusPending, TotalAmount: money.New(15000, "IDR")}}
+		svc := newWebhookTestSvc(t, repo)
+
+		resp, _ := svc.HandleWebhook(context.Background(), gateway.SimulatorName, tt.header, []byte(body))
+		if resp.Code != http.StatusUnauthorized {
+			t.Errorf("%s: code = %d, want %d", tt.name, resp.Code, http.StatusUnauthorized)
+		}
+		if len(repo.settled) > 0 || len(repo.late) > 0 {
+			t.Errorf("%s: order was updated", tt.name)
+		}
+	}
+}
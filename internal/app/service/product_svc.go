@@ -3,26 +3,69 @@ package service
 import (
 	"be-shop/internal/app/models"
 	"be-shop/internal/app/repo/postgres"
+	"be-shop/pkg/money"
 	"context"
+	"errors"
 	"log/slog"
-	"math"
 	"net/http"
+	"strings"
 
 	"go.uber.org/dig"
 )
 
 type (
 	UpdatePriceReq struct {
-		Price float64 `json:"price" validate:"required,gt=0,numeric"`
+		Price money.Money `json:"price" validate:"required,gt=0"`
 	}
+
+	AdjustStockReq struct {
+		Delta  int    `json:"delta" validate:"required"`
+		Reason string `json:"reason" validate:"required,max=255"`
+	}
+	// GetProductsReq lists products. Prices are decimal strings in major
+	// units, the same form products are priced in.
+	GetProductsReq struct {
+		models.PaginationRequest
+		CategoryID int64  `query:"category_id" validate:"omitempty,gt=0"`
+		MinPrice   string `query:"min_price" validate:"omitempty,numeric"`
+		MaxPrice   string `query:"max_price" validate:"omitempty,numeric"`
+		Sort       string `query:"sort" validate:"omitempty,oneof=newest price_asc price_desc name_asc name_desc"`
+	}
+
+	// SearchProductsReq is a shopper's free-text product search. Query accepts
+	// quoted phrases, "or" and -exclusions.
+	SearchProductsReq struct {
+		models.PaginationRequest
+		Query string `query:"q" validate:"required,max=100"`
+	}
+
+	RenameCategoryReq struct {
+		Name string `json:"name" validate:"required,max=255"`
+	}
+
+	// MoveCategoryReq moves a category under ParentID, or to the top level
+	// when ParentID is null.
+	MoveCategoryReq struct {
+		ParentID *int `json:"parent_id" validate:"omitempty,gt=0"`
+	}
+
 	ProductSvc interface {
 		CreateProduct(ctx context.Context, req models.Product) (resp models.DefaultResponse, err error)
-		GetAllProduct(ctx context.Context, req models.PaginationRequest) (resp models.DefaultResponse, err error)
+		GetAllProduct(ctx context.Context, req GetProductsReq) (resp models.DefaultResponse, err error)
+		GetDeletedProducts(ctx context.Context, req GetProductsReq) (resp models.DefaultResponse, err error)
+		SearchProducts(ctx context.Context, req SearchProductsReq) (resp models.DefaultResponse, err error)
 		GetProductByID(ctx context.Context, id int64) (resp models.DefaultResponse, err error)
 		GetProductByCategoryID(ctx context.Context, id int64) (resp models.DefaultResponse, err error)
 		UpdateProductPrice(ctx context.Context, id int64, req UpdatePriceReq) (resp models.DefaultResponse, err error)
 		DeleteProduct(ctx context.Context, id int64) (resp models.DefaultResponse, err error)
-		CreateCategory(ctx context.Context, name string) (resp models.DefaultResponse, err error)
+		RestoreProduct(ctx context.Context, id int64) (resp models.DefaultResponse, err error)
+		AdjustProductStock(ctx context.Context, id int64, req AdjustStockReq) (resp models.DefaultResponse, err error)
+		GetStockAdjustments(ctx context.Context, id int64) (resp models.DefaultResponse, err error)
+		GetCategories(ctx context.Context) (resp models.DefaultResponse, err error)
+		CreateCategory(ctx context.Context, req models.Category) (resp models.DefaultResponse, err error)
+		RenameCategory(ctx context.Context, id int, req RenameCategoryReq) (resp models.DefaultResponse, err error)
+		MoveCategory(ctx context.Context, id int, req MoveCategoryReq) (resp models.DefaultResponse, err error)
+		DeleteCategory(ctx context.Context, id int) (resp models.DefaultResponse, err error)
 	}
 
 	ProductSvcImpl struct {
@@ -43,6 +86,7 @@ func (p *ProductSvcImpl) CreateProduct(ctx context.Context, req models.Product)
 		resp.Code = http.StatusBadGateway
 	}
 
+	req.Price = req.Price.OrDefault()
 	id, err := p.ProductRepo.CreateProduct(ctx, req)
 	if err != nil {
 		slog.ErrorContext(ctx, "[ProductSvcImpl.CreateProduct] error while CreateProduct err", "%v", err.Error())
@@ -67,43 +111,187 @@ func (p *ProductSvcImpl) GetProductByID(ctx context.Context, id int64) (resp mod
 
 	product, err := p.ProductRepo.GetProductByID(ctx, id)
 	if err != nil {
-		slog.ErrorContext(ctx, "[ProductSvcImpl.GetProductByID] error while GetProductByID err: %v", err.Error())
+		slog.ErrorContext(ctx, "[ProductSvcImpl.GetProductByID] error while GetProductByID err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrProductNotFound) {
+			resp.Message = "Product not found"
+			resp.Code = http.StatusNotFound
+		}
 		return
 	}
 
+	trails, err := p.breadcrumbTrails(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.GetProductByID] error while breadcrumbTrails err", "%v", err.Error())
+		return
+	}
+	product.SetBreadcrumbs(trails)
+
 	resp.Message = "Product fetched successfully"
 	resp.Code = http.StatusOK
 	resp.Data = product
 	return
 }
 
-func (p *ProductSvcImpl) GetAllProduct(ctx context.Context, req models.PaginationRequest) (resp models.DefaultResponse, err error) {
+func (p *ProductSvcImpl) GetAllProduct(ctx context.Context, req GetProductsReq) (resp models.DefaultResponse, err error) {
+	return p.listProducts(ctx, req, false)
+}
+
+// GetDeletedProducts lists soft-deleted products for admins, with the same
+// filters and pagination as the catalog.
+func (p *ProductSvcImpl) GetDeletedProducts(ctx context.Context, req GetProductsReq) (resp models.DefaultResponse, err error) {
+	return p.listProducts(ctx, req, true)
+}
+
+func (p *ProductSvcImpl) listProducts(ctx context.Context, req GetProductsReq, deleted bool) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to get products"
+		resp.Code = http.StatusBadGateway
+	}
+
+	filter := models.ProductFilter{
+		Deleted:    deleted,
+		Search:     req.Search,
+		CategoryID: req.CategoryID,
+		Sort:       req.Sort,
+		Limit:      req.Limit,
+		Offset:     (req.Page - 1) * req.Limit,
+	}
+	if req.MinPrice != "" {
+		minPrice, parseErr := money.Parse(req.MinPrice, "")
+		if parseErr != nil || minPrice.Amount < 0 {
+			resp.Message = "Invalid min_price"
+			resp.Code = http.StatusBadRequest
+			return
+		}
+		filter.MinPrice = &minPrice
+	}
+	if req.MaxPrice != "" {
+		maxPrice, parseErr := money.Parse(req.MaxPrice, "")
+		if parseErr != nil || maxPrice.Amount < 0 {
+			resp.Message = "Invalid max_price"
+			resp.Code = http.StatusBadRequest
+			return
+		}
+		filter.MaxPrice = &maxPrice
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && filter.MinPrice.Amount > filter.MaxPrice.Amount {
+		resp.Message = "min_price cannot be greater than max_price"
+		resp.Code = http.StatusBadRequest
+		return
+	}
+
+	if req.UseCursor {
+		return p.getProductsByCursor(ctx, req, filter)
+	}
+
+	totalItem, products, err := p.ProductRepo.GetAllProduct(ctx, filter)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.listProducts] error while GetAllProduct err", "%v", err.Error())
+		return
+	}
+
+	trails, err := p.breadcrumbTrails(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.listProducts] error while breadcrumbTrails err", "%v", err.Error())
+		return
+	}
+	for i := range products {
+		products[i].SetBreadcrumbs(trails)
+	}
+
+	resp.Message = "Products fetched successfully"
+	resp.Code = http.StatusOK
+	resp.Data = models.DefaultPaginationResponseData{
+		Results:         products,
+		DefaultMetaData: models.NewPageMetaData(req.Page, req.Limit, totalItem),
+	}
+	return
+}
+
+// getProductsByCursor serves GetAllProduct in cursor mode. A cursor only
+// makes sense for the sort it was issued under, so one from another sort is
+// rejected rather than silently skipping or repeating products.
+func (p *ProductSvcImpl) getProductsByCursor(ctx context.Context, req GetProductsReq, filter models.ProductFilter) (resp models.DefaultResponse, err error) {
 	{
 		resp.Message = "Failed to get products"
 		resp.Code = http.StatusBadGateway
-		req.Page = (req.Page - 1) * req.Limit
 	}
-	totalItem, products, err := p.ProductRepo.GetAllProduct(ctx, req.Page, req.Limit)
+
+	sort := req.Sort
+	if sort == "" {
+		sort = models.ProductSortNewest
+	}
+	cursor, err := models.DecodeCursor(req.Cursor)
+	if err == nil && !cursor.IsStart() && cursor.Sort != sort {
+		err = models.ErrInvalidCursor
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.getProductsByCursor] error while DecodeCursor err", "%v", err.Error())
+		resp.Message = "Invalid cursor"
+		resp.Code = http.StatusBadRequest
+		return
+	}
+	cursor.Sort = sort
+
+	products, page, err := p.ProductRepo.GetProductsByCursor(ctx, filter, cursor)
 	if err != nil {
-		slog.ErrorContext(ctx, "[ProductSvcImpl.GetAllProduct] error while GetAllProduct err", "%v", err.Error())
+		slog.ErrorContext(ctx, "[ProductSvcImpl.getProductsByCursor] error while GetProductsByCursor err", "%v", err.Error())
 		return
 	}
 
+	trails, err := p.breadcrumbTrails(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.getProductsByCursor] error while breadcrumbTrails err", "%v", err.Error())
+		return
+	}
+	for i := range products {
+		products[i].SetBreadcrumbs(trails)
+	}
+
 	resp.Message = "Products fetched successfully"
 	resp.Code = http.StatusOK
 	resp.Data = models.DefaultPaginationResponseData{
-		Results: products,
-		DefaultMetaData: models.DefaultMetaData{
-			Page:        uint(req.Page + 1),
-			TotalPages:  uint(math.Ceil(float64(totalItem) / float64(req.Limit))),
-			Limit:       uint(req.Limit),
-			TotalItems:  uint(totalItem),
-			HasNext:     req.Page < int(math.Ceil(float64(totalItem)/float64(req.Limit))),
-			HasPrevious: req.Page > 1,
-		},
+		Results:         products,
+		DefaultMetaData: models.NewCursorMetaData(req.Limit, page),
 	}
 	return
+}
+
+func (p *ProductSvcImpl) SearchProducts(ctx context.Context, req SearchProductsReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to search products"
+		resp.Code = http.StatusBadGateway
+	}
+
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		resp.Message = "Search query is required"
+		resp.Code = http.StatusBadRequest
+		return
+	}
+
+	totalItem, results, err := p.ProductRepo.SearchProducts(ctx, query, req.Limit, (req.Page-1)*req.Limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.SearchProducts] error while SearchProducts err", "%v", err.Error())
+		return
+	}
+
+	trails, err := p.breadcrumbTrails(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.SearchProducts] error while breadcrumbTrails err", "%v", err.Error())
+		return
+	}
+	for i := range results {
+		results[i].SetBreadcrumbs(trails)
+	}
 
+	resp.Message = "Products fetched successfully"
+	resp.Code = http.StatusOK
+	resp.Data = models.DefaultPaginationResponseData{
+		Results:         results,
+		DefaultMetaData: models.NewPageMetaData(req.Page, req.Limit, totalItem),
+	}
+	return
 }
 
 func (p *ProductSvcImpl) UpdateProductPrice(ctx context.Context, id int64, req UpdatePriceReq) (resp models.DefaultResponse, err error) {
@@ -112,7 +300,7 @@ func (p *ProductSvcImpl) UpdateProductPrice(ctx context.Context, id int64, req U
 		resp.Code = http.StatusBadRequest
 	}
 
-	err = p.ProductRepo.UpdateProductPrice(ctx, id, req.Price)
+	err = p.ProductRepo.UpdateProductPrice(ctx, id, req.Price.OrDefault())
 	if err != nil {
 		slog.ErrorContext(ctx, "[ProductSvcImpl.UpdateProductPrice] error while UpdateProductPrice err", "%v", err.Error())
 		return
@@ -132,6 +320,10 @@ func (p *ProductSvcImpl) DeleteProduct(ctx context.Context, id int64) (resp mode
 	err = p.ProductRepo.SoftDeleteProduct(ctx, id)
 	if err != nil {
 		slog.ErrorContext(ctx, "[ProductSvcImpl.DeleteProduct] error while SoftDeleteProduct err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrProductNotFound) {
+			resp.Message = "Product not found"
+			resp.Code = http.StatusNotFound
+		}
 		return
 	}
 
@@ -140,17 +332,100 @@ func (p *ProductSvcImpl) DeleteProduct(ctx context.Context, id int64) (resp mode
 	return
 }
 
+func (p *ProductSvcImpl) RestoreProduct(ctx context.Context, id int64) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to restore product"
+		resp.Code = http.StatusBadGateway
+	}
+
+	err = p.ProductRepo.RestoreProduct(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.RestoreProduct] error while RestoreProduct err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrProductNotFound) {
+			resp.Message = "Deleted product not found"
+			resp.Code = http.StatusNotFound
+		}
+		return
+	}
+
+	resp.Message = "Product restored successfully"
+	resp.Code = http.StatusOK
+	return
+}
+
+func (p *ProductSvcImpl) AdjustProductStock(ctx context.Context, id int64, req AdjustStockReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to adjust product stock"
+		resp.Code = http.StatusBadGateway
+		req.Reason = strings.TrimSpace(req.Reason)
+	}
+
+	adjustment, err := p.ProductRepo.AdjustStock(ctx, id, req.Delta, req.Reason)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.AdjustProductStock] error while AdjustStock err", "%v", err.Error())
+		switch {
+		case errors.Is(err, postgres.ErrProductNotFound):
+			resp.Message = "Product not found"
+			resp.Code = http.StatusNotFound
+		case errors.Is(err, postgres.ErrNegativeStock):
+			resp.Message = "Stock cannot be negative"
+			resp.Code = http.StatusConflict
+		}
+		resp.Error = err.Error()
+		return
+	}
+
+	resp.Message = "Product stock adjusted successfully"
+	resp.Code = http.StatusOK
+	resp.Data = adjustment
+	return
+}
+
+func (p *ProductSvcImpl) GetStockAdjustments(ctx context.Context, id int64) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to get stock adjustments"
+		resp.Code = http.StatusBadGateway
+	}
+
+	adjustments, err := p.ProductRepo.GetStockAdjustments(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.GetStockAdjustments] error while GetStockAdjustments err", "%v", err.Error())
+		return
+	}
+
+	resp.Message = "Stock adjustments fetched successfully"
+	resp.Code = http.StatusOK
+	resp.Data = adjustments
+	return
+}
+
+// GetProductByCategoryID lists the products in a category and in every
+// category below it.
 func (p *ProductSvcImpl) GetProductByCategoryID(ctx context.Context, id int64) (resp models.DefaultResponse, err error) {
 	{
 		resp.Message = "Failed to get product"
 		resp.Code = http.StatusBadRequest
 	}
 
+	trails, err := p.breadcrumbTrails(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.GetProductByCategoryID] error while breadcrumbTrails err", "%v", err.Error())
+		return
+	}
+	if _, ok := trails[int(id)]; !ok {
+		resp.Message = "Category not found"
+		resp.Code = http.StatusNotFound
+		return
+	}
+
 	product, err := p.ProductRepo.GetProductByCategoryID(ctx, id)
 	if err != nil {
 		slog.ErrorContext(ctx, "[ProductSvcImpl.GetProductByCategoryID] error while GetProductByCategoryID err", "%v", err.Error())
 		return
 	}
+	for i := range product {
+		product[i].SetBreadcrumbs(trails)
+	}
 
 	resp.Message = "Product fetched successfully"
 	resp.Code = http.StatusOK
@@ -158,15 +433,34 @@ func (p *ProductSvcImpl) GetProductByCategoryID(ctx context.Context, id int64) (
 	return
 }
 
-func (p *ProductSvcImpl) CreateCategory(ctx context.Context, name string) (resp models.DefaultResponse, err error) {
+func (p *ProductSvcImpl) GetCategories(ctx context.Context) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to get categories"
+		resp.Code = http.StatusBadGateway
+	}
+
+	categories, err := p.CategoryRepo.GetCategories(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.GetCategories] error while GetCategories err", "%v", err.Error())
+		return
+	}
+
+	resp.Message = "Categories fetched successfully"
+	resp.Code = http.StatusOK
+	resp.Data = models.CategoryTree(categories)
+	return
+}
+
+func (p *ProductSvcImpl) CreateCategory(ctx context.Context, req models.Category) (resp models.DefaultResponse, err error) {
 	{
 		resp.Message = "Failed to create category"
 		resp.Code = http.StatusBadRequest
 	}
 
-	id, err := p.CategoryRepo.CreateCategory(ctx, name)
+	id, err := p.CategoryRepo.CreateCategory(ctx, strings.TrimSpace(req.Name), req.ParentID)
 	if err != nil {
 		slog.ErrorContext(ctx, "[ProductSvcImpl.CreateCategory] error while CreateCategory err", "%v", err.Error())
+		categoryErrorResponse(&resp, err)
 		return
 	}
 
@@ -179,3 +473,92 @@ func (p *ProductSvcImpl) CreateCategory(ctx context.Context, name string) (resp
 	}
 	return
 }
+
+func (p *ProductSvcImpl) RenameCategory(ctx context.Context, id int, req RenameCategoryReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to rename category"
+		resp.Code = http.StatusBadGateway
+	}
+
+	err = p.CategoryRepo.RenameCategory(ctx, id, strings.TrimSpace(req.Name))
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.RenameCategory] error while RenameCategory err", "%v", err.Error())
+		categoryErrorResponse(&resp, err)
+		return
+	}
+
+	resp.Message = "Category renamed successfully"
+	resp.Code = http.StatusOK
+	return
+}
+
+// MoveCategory re-parents a category together with its whole subtree.
+func (p *ProductSvcImpl) MoveCategory(ctx context.Context, id int, req MoveCategoryReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to move category"
+		resp.Code = http.StatusBadGateway
+	}
+
+	err = p.CategoryRepo.MoveCategory(ctx, id, req.ParentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.MoveCategory] error while MoveCategory err", "%v", err.Error())
+		categoryErrorResponse(&resp, err)
+		return
+	}
+
+	resp.Message = "Category moved successfully"
+	resp.Code = http.StatusOK
+	return
+}
+
+func (p *ProductSvcImpl) DeleteCategory(ctx context.Context, id int) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to delete category"
+		resp.Code = http.StatusBadGateway
+	}
+
+	err = p.CategoryRepo.DeleteCategory(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "[ProductSvcImpl.DeleteCategory] error while DeleteCategory err", "%v", err.Error())
+		categoryErrorResponse(&resp, err)
+		return
+	}
+
+	resp.Message = "Category deleted successfully"
+	resp.Code = http.StatusOK
+	return
+}
+
+// breadcrumbTrails loads the category tree as breadcrumb trails keyed by
+// category id. The tree is small, so it is cheaper to read it whole than to
+// walk up from each product.
+func (p *ProductSvcImpl) breadcrumbTrails(ctx context.Context) (trails map[int][]models.CategoryCrumb, err error) {
+	categories, err := p.CategoryRepo.GetCategories(ctx)
+	if err != nil {
+		return
+	}
+	return models.CategoryBreadcrumbs(categories), nil
+}
+
+// categoryErrorResponse sets the status and message for a category repo
+// error, leaving resp untouched for unexpected errors.
+func categoryErrorResponse(resp *models.DefaultResponse, err error) {
+	resp.Error = err.Error()
+	switch {
+	case errors.Is(err, postgres.ErrCategoryNotFound):
+		resp.Message = "Category not found"
+		resp.Code = http.StatusNotFound
+	case errors.Is(err, postgres.ErrCategoryNameTaken):
+		resp.Message = "A category with this name already exists here"
+		resp.Code = http.StatusConflict
+	case errors.Is(err, postgres.ErrCategoryCycle):
+		resp.Message = "Category cannot be moved under itself or its subcategories"
+		resp.Code = http.StatusConflict
+	case errors.Is(err, postgres.ErrCategoryHasChildren):
+		resp.Message = "Category still has subcategories"
+		resp.Code = http.StatusConflict
+	case errors.Is(err, postgres.ErrCategoryHasProducts):
+		resp.Message = "Category still has products"
+		resp.Code = http.StatusConflict
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo/postgres"
+	"be-shop/pkg/money"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+type (
+	CreatePromotionReq struct {
+		Code         string               `json:"code" validate:"required,alphanum,max=50"`
+		Description  string               `json:"description" validate:"max=255"`
+		Type         models.PromotionType `json:"type" validate:"required,oneof=percentage fixed"`
+		Percent      int                  `json:"percent" validate:"required_if=Type percentage,gte=0,lte=100"`
+		Amount       money.Money          `json:"amount" validate:"required_if=Type fixed,gte=0"`
+		MaxDiscount  *money.Money         `json:"max_discount"`
+		MinSpend     money.Money          `json:"min_spend" validate:"gte=0"`
+		UsageLimit   *int                 `json:"usage_limit" validate:"omitempty,gt=0"`
+		PerUserLimit *int                 `json:"per_user_limit" validate:"omitempty,gt=0"`
+		StartsAt     *time.Time           `json:"starts_at"`
+		EndsAt       *time.Time           `json:"ends_at"`
+		ProductIDs   []int64              `json:"product_ids" validate:"dive,gt=0"`
+		CategoryIDs  []int64              `json:"category_ids" validate:"dive,gt=0"`
+	}
+
+	PromotionSvc interface {
+		CreatePromotion(ctx context.Context, req CreatePromotionReq) (resp models.DefaultResponse, err error)
+		GetPromotions(ctx context.Context, req models.PaginationRequest) (resp models.DefaultResponse, err error)
+	}
+
+	PromotionSvcImpl struct {
+		dig.In
+
+		PromotionRepo postgres.PromotionRepo
+	}
+)
+
+func NewPromotionSvc(impl PromotionSvcImpl) PromotionSvc {
+	return &impl
+}
+
+func (p *PromotionSvcImpl) CreatePromotion(ctx context.Context, req CreatePromotionReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to create promotion"
+		resp.Code = http.StatusBadGateway
+	}
+
+	promotion := models.Promotion{
+		Code:         strings.ToUpper(req.Code),
+		Description:  req.Description,
+		Type:         req.Type,
+		Amount:       req.Amount.OrDefault(),
+		MinSpend:     req.MinSpend.OrDefault(),
+		UsageLimit:   req.UsageLimit,
+		PerUserLimit: req.PerUserLimit,
+		StartsAt:     time.Now(),
+		EndsAt:       req.EndsAt,
+		ProductIDs:   req.ProductIDs,
+		CategoryIDs:  req.CategoryIDs,
+	}
+	if req.StartsAt != nil {
+		promotion.StartsAt = *req.StartsAt
+	}
+
+	if req.Type == models.PromotionTypePercentage {
+		promotion.Percent = req.Percent
+		promotion.Amount = money.Money{Currency: promotion.MinSpend.Currency}
+		if req.MaxDiscount != nil {
+			maxDiscount := req.MaxDiscount.OrDefault()
+			promotion.MaxDiscount = &maxDiscount
+		}
+	}
+
+	switch {
+	case req.Type == models.PromotionTypeFixed && !promotion.Amount.IsPositive():
+		err = errors.New("amount must be greater than zero")
+	case promotion.Amount.Currency != promotion.MinSpend.Currency,
+		promotion.MaxDiscount != nil && promotion.MaxDiscount.Currency != promotion.MinSpend.Currency:
+		err = money.ErrCurrencyMismatch
+	case promotion.MaxDiscount != nil && !promotion.MaxDiscount.IsPositive():
+		err = errors.New("max_discount must be greater than zero")
+	case promotion.EndsAt != nil && !promotion.EndsAt.After(promotion.StartsAt):
+		err = errors.New("ends_at must be after starts_at")
+	}
+	if err != nil {
+		resp.Message = "Invalid promotion"
+		resp.Code = http.StatusBadRequest
+		resp.Error = err.Error()
+		return
+	}
+
+	id, err := p.PromotionRepo.CreatePromotion(ctx, promotion)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionSvcImpl.CreatePromotion] error while CreatePromotion err", "%v", err.Error())
+		if errors.Is(err, postgres.ErrPromotionCodeTaken) {
+			resp.Message = "Promotion code already exists"
+			resp.Code = http.StatusConflict
+		}
+		return
+	}
+
+	resp.Message = "Promotion created successfully"
+	resp.Code = http.StatusCreated
+	resp.Data = struct {
+		ID   int    `json:"id"`
+		Code string `json:"code"`
+	}{
+		ID:   id,
+		Code: promotion.Code,
+	}
+	return
+}
+
+func (p *PromotionSvcImpl) GetPromotions(ctx context.Context, req models.PaginationRequest) (resp models.DefaultResponse, err error) {
+	{
+		resp.Message = "Failed to get promotions"
+		resp.Code = http.StatusBadGateway
+	}
+
+	totalItem, promotions, err := p.PromotionRepo.GetPromotions(ctx, req.Limit, (req.Page-1)*req.Limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "[PromotionSvcImpl.GetPromotions] error while GetPromotions err", "%v", err.Error())
+		return
+	}
+
+	resp.Message = "Promotions fetched successfully"
+	resp.Code = http.StatusOK
+	resp.Data = models.DefaultPaginationResponseData{
+		Results:         promotions,
+		DefaultMetaData: models.NewPageMetaData(req.Page, req.Limit, totalItem),
+	}
+	return
+}
+
+// isPromotionError reports whether err is a promotion rule the cart or user
+// does not satisfy, as opposed to a failure to evaluate the promotion.
+func isPromotionError(err error) bool {
+	for _, target := range []error{
+		models.ErrPromotionInactive,
+		models.ErrPromotionNotStarted,
+		models.ErrPromotionEnded,
+		models.ErrPromotionUsageLimit,
+		models.ErrPromotionUserLimit,
+		models.ErrPromotionNotApplicable,
+		models.ErrPromotionMinSpend,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,22 +1,35 @@
 package service
 
 import (
+	"be-shop/internal/app/infra"
 	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo"
 	"be-shop/internal/app/repo/postgres"
 	"be-shop/internal/app/service/utils"
+	"be-shop/pkg/mailer"
+	"be-shop/pkg/middleware"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"go.uber.org/dig"
 )
 
+// backgroundMailTimeout bounds mail sent after the request that triggered it
+// has already been answered.
+const backgroundMailTimeout = 30 * time.Second
+
 type (
 	LoginReq struct {
 		Identity string `json:"identity" validate:"required"`
 		Password string `json:"password" validate:"required"`
+		// IP is the client address, set by the controller.
+		IP string `json:"-"`
 	}
 
 	JWTData struct {
@@ -25,15 +38,87 @@ type (
 		Usename string `json:"username"`
 	}
 
+	RefreshTokenReq struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+
+	VerifyEmailReq struct {
+		Token string `query:"token" validate:"required"`
+	}
+
+	UpdateProfileReq struct {
+		Username string `json:"username" validate:"required,excludes=@,max=255"`
+	}
+
+	// ChangePasswordReq enforces the same password policy as models.User.
+	ChangePasswordReq struct {
+		CurrentPassword string `json:"current_password" validate:"required"`
+		NewPassword     string `json:"new_password" validate:"required,min=8,max=20,uppercase,lowercase,number,specialchar,nefield=CurrentPassword"`
+	}
+
+	ChangeEmailReq struct {
+		Email    string `json:"email" validate:"required,email,max=255"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	ForgotPasswordReq struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	// ResetPasswordReq enforces the same password policy as models.User.
+	ResetPasswordReq struct {
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required,min=8,max=20,uppercase,lowercase,number,specialchar"`
+	}
+
+	LogoutReq struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// TokenPair is returned on login and refresh. Token is the short-lived
+	// access token; RefreshToken can be exchanged once for a new pair.
+	TokenPair struct {
+		Token         string `json:"token"`
+		Expire        string `json:"expire"`
+		RefreshToken  string `json:"refresh_token"`
+		RefreshExpire string `json:"refresh_expire"`
+	}
+
+	UpdateRoleReq struct {
+		Role models.Role `json:"role" validate:"required,oneof=customer staff admin"`
+	}
+
 	UserSvc interface {
 		UserRegistration(ctx context.Context, req models.User) (err error)
 		UserLogin(ctx context.Context, req LoginReq) (resp models.DefaultResponse, err error)
+		RefreshToken(ctx context.Context, req RefreshTokenReq) (resp models.DefaultResponse, err error)
+		Logout(ctx context.Context, req LogoutReq) (resp models.DefaultResponse, err error)
+		LogoutAll(ctx context.Context) (resp models.DefaultResponse, err error)
+		VerifyEmail(ctx context.Context, req VerifyEmailReq) (resp models.DefaultResponse, err error)
+		ResendVerification(ctx context.Context) (resp models.DefaultResponse, err error)
+		UnlockUser(ctx context.Context, id int) (resp models.DefaultResponse, err error)
+		GetProfile(ctx context.Context) (resp models.DefaultResponse, err error)
+		UpdateProfile(ctx context.Context, req UpdateProfileReq) (resp models.DefaultResponse, err error)
+		ChangePassword(ctx context.Context, req ChangePasswordReq) (resp models.DefaultResponse, err error)
+		ChangeEmail(ctx context.Context, req ChangeEmailReq) (resp models.DefaultResponse, err error)
+		ConfirmEmailChange(ctx context.Context, req VerifyEmailReq) (resp models.DefaultResponse, err error)
+		ForgotPassword(ctx context.Context, req ForgotPasswordReq) (resp models.DefaultResponse, err error)
+		ResetPassword(ctx context.Context, req ResetPasswordReq) (resp models.DefaultResponse, err error)
+		UpdateUserRole(ctx context.Context, id int, req UpdateRoleReq) (resp models.DefaultResponse, err error)
 	}
 
 	UserSvcImpl struct {
 		dig.In
 
-		UserRepo postgres.UserRepo
+		UserRepo          postgres.UserRepo
+		RefreshTokenRepo  postgres.RefreshTokenRepo
+		RevocationStore   repo.RevocationStore
+		LoginAttemptStore repo.LoginAttemptStore
+		UserTokenRepo     postgres.UserTokenRepo
+		Mailer            mailer.Mailer
+		AuthCfg           *infra.AuthCfg
+		JwtCfg            *infra.JwtCfg
+		KeyManager        *utils.KeyManager
 	}
 )
 
@@ -46,6 +131,7 @@ func (u *UserSvcImpl) UserRegistration(ctx context.Context, req models.User) (er
 	{
 		req.Email = strings.ToLower(strings.TrimSpace(req.Email))
 		req.Username = strings.ToLower(strings.TrimSpace(req.Username))
+		req.Role = models.RoleCustomer
 		req.Password, err = utils.HashPassword(req.Password)
 		if err != nil {
 			slog.ErrorContext(ctx, fmt.Sprintf("[service][HashPassword] err : %v", err))
@@ -53,15 +139,115 @@ func (u *UserSvcImpl) UserRegistration(ctx context.Context, req models.User) (er
 		}
 	}
 
-	_, err = u.UserRepo.CreateUser(ctx, req)
+	req.ID, err = u.UserRepo.CreateUser(ctx, req)
 	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserRegistration] err : %v", err.Error()))
 		return err
 	}
 
+	// the account exists either way; the user can ask for another link
+	if err := u.sendVerificationEmail(ctx, req); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserRegistration][sendVerificationEmail] err : %v", err.Error()))
+	}
+
 	return nil
 }
 
+// VerifyEmail marks the email of the token's owner as verified.
+func (u *UserSvcImpl) VerifyEmail(ctx context.Context, req VerifyEmailReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+	}
+
+	_, err = u.UserRepo.VerifyEmail(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][VerifyEmail][VerifyEmail] err : %v", err))
+		if errors.Is(err, postgres.ErrUserTokenInvalid) {
+			resp.Code = http.StatusBadRequest
+			resp.Message = "invalid or expired verification token"
+			resp.Error = err.Error()
+		}
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "email has been verified"
+	return
+}
+
+// ResendVerification mails a new verification link to the signed in user, at
+// most once every AuthCfg.VerificationResendInterval. Earlier links stop
+// working.
+func (u *UserSvcImpl) ResendVerification(ctx context.Context) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+	}
+
+	userCtx := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+	if userCtx.EmailVerified {
+		resp.Code = http.StatusConflict
+		resp.Message = "email is already verified"
+		return
+	}
+
+	lastSentAt, err := u.UserTokenRepo.GetLastUserTokenAt(ctx, userCtx.UserID, models.TokenPurposeEmailVerification)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ResendVerification][GetLastUserTokenAt] err : %v", err))
+		return
+	}
+	if lastSentAt != nil {
+		if wait := time.Until(lastSentAt.Add(u.AuthCfg.VerificationResendInterval)); wait > 0 {
+			err = &RetryAfterError{RetryAfter: wait}
+			resp.Code = http.StatusTooManyRequests
+			resp.Message = "verification email was sent recently, please wait before asking again"
+			resp.Error = err.Error()
+			return
+		}
+	}
+
+	err = u.sendVerificationEmail(ctx, models.User{
+		ID:       userCtx.UserID,
+		Email:    userCtx.Email,
+		Username: userCtx.Username,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ResendVerification][sendVerificationEmail] err : %v", err))
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "verification email has been sent"
+	return
+}
+
+func (u *UserSvcImpl) sendVerificationEmail(ctx context.Context, user models.User) (err error) {
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return
+	}
+
+	_, err = u.UserTokenRepo.CreateUserToken(ctx, models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposeEmailVerification,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(u.AuthCfg.EmailVerificationTTL),
+	})
+	if err != nil {
+		return
+	}
+
+	return u.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your be-shop email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.Username, u.AuthCfg.EmailVerificationTTL, tokenLink(u.AuthCfg.EmailVerificationURL, token),
+		),
+	})
+}
+
 func (u *UserSvcImpl) UserLogin(ctx context.Context, req LoginReq) (resp models.DefaultResponse, err error) {
 	{
 		resp.Code = http.StatusOK
@@ -69,31 +255,90 @@ func (u *UserSvcImpl) UserLogin(ctx context.Context, req LoginReq) (resp models.
 		req.Identity = strings.ToLower(strings.TrimSpace(req.Identity))
 	}
 
-	user, err := u.UserRepo.GetUserByEmail(ctx, req.Identity)
-	if err != nil {
-		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][FindUserByEmail] err : %v", err))
-		err = fmt.Errorf("invalid email or password")
-		resp.Code = http.StatusUnauthorized
-		resp.Message = "invalid email or password"
-		resp.Error = err
+	user, err := u.UserRepo.GetUserByIdentity(ctx, req.Identity)
+	found := err == nil
+	if err != nil && !errors.Is(err, postgres.ErrUserNotFound) {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][GetUserByIdentity] err : %v", err))
+		err = fmt.Errorf("internal server error, we will fix it soon")
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+		return
+	}
+
+	// unknown identities are throttled like real accounts, so lockouts do
+	// not tell which identities exist either
+	accountKey := "identity:" + req.Identity
+	if found {
+		accountKey = fmt.Sprintf("user:%d", user.ID)
+	}
+	ipKey := ""
+	if req.IP != "" {
+		ipKey = "ip:" + req.IP
+	}
+
+	// the attempt counts as a failure from here on and is taken back below
+	// once the password turns out to be right
+	if resp.Code, err = u.attemptLogin(ctx, accountKey, ipKey); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][attemptLogin] err : %v", err))
+		resp.Message = "too many failed login attempts, please try again later"
+		if resp.Code == http.StatusInternalServerError {
+			resp.Message = "internal server error, we will fix it soon"
+		}
+		resp.Error = err.Error()
 		return
 	}
 
-	if err = utils.VerifyPassword(req.Password, user.Password); err != nil {
+	if found {
+		err = utils.VerifyPassword(req.Password, user.Password)
+	} else {
+		// take as long as a wrong password would, so timing does not tell
+		// which identities exist
+		utils.VerifyDummyPassword(req.Password)
+		err = postgres.ErrUserNotFound
+	}
+	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][VerifyPassword] err : %v", err))
-		err = fmt.Errorf("invalid email or password")
+		err = fmt.Errorf("invalid email, username or password")
 		resp.Code = http.StatusUnauthorized
-		resp.Message = "invalid email or password"
+		resp.Message = "invalid email, username or password"
 		resp.Error = err
 		return
 	}
 
-	token, exp, err := utils.Sign(JWTData{
-		Email:   req.Identity,
-		UserID:  user.ID,
-		Usename: user.Username,
+	if resetErr := u.LoginAttemptStore.Reset(ctx, accountKey); resetErr != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][Reset] err : %v", resetErr))
+	}
+	if ipKey != "" {
+		if forgiveErr := u.LoginAttemptStore.Forgive(ctx, ipKey); forgiveErr != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][Forgive] err : %v", forgiveErr))
+		}
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][GenerateOpaqueToken] err : %v", err))
+		err = fmt.Errorf("internal server error, we will fix it soon")
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+		return
+	}
+
+	refreshExp := time.Now().Add(u.JwtCfg.RefreshTokenTTL)
+	_, err = u.RefreshTokenRepo.CreateRefreshToken(ctx, models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: refreshHash,
+		FamilyID:  utils.RandomString(32),
+		ExpiresAt: refreshExp,
 	})
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][CreateRefreshToken] err : %v", err))
+		err = fmt.Errorf("internal server error, we will fix it soon")
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+		return
+	}
 
+	tokens, err := u.signTokens(user, refreshToken, refreshExp)
 	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("[service][UserLogin][Sign] err : %v", err))
 		err = fmt.Errorf("internal server error, we will fix it soon")
@@ -102,13 +347,575 @@ func (u *UserSvcImpl) UserLogin(ctx context.Context, req LoginReq) (resp models.
 		return
 	}
 
+	resp.Data = tokens
+
+	return
+}
+
+// attemptLogin counts a login attempt against the account and the IP address,
+// refusing it while the account is locked (423) or the account or IP address
+// must still wait (429). The error is a RetryAfterError in those cases. A
+// refused attempt is not counted.
+func (u *UserSvcImpl) attemptLogin(ctx context.Context, accountKey, ipKey string) (code int, err error) {
+	window := u.loginWindow()
+
+	wait, locked, err := u.LoginAttemptStore.Attempt(ctx, accountKey, window, repo.LoginLimit{
+		MaxFailures: u.AuthCfg.LoginMaxFailures,
+		Lockout:     u.AuthCfg.LoginLockout,
+		DelayAfter:  u.AuthCfg.LoginDelayAfter,
+		Delay:       u.AuthCfg.LoginDelay,
+		MaxDelay:    u.AuthCfg.LoginMaxDelay,
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if locked {
+		return http.StatusLocked, &RetryAfterError{RetryAfter: wait}
+	}
+	if wait > 0 {
+		return http.StatusTooManyRequests, &RetryAfterError{RetryAfter: wait}
+	}
+
+	if ipKey == "" {
+		return http.StatusOK, nil
+	}
+	wait, _, err = u.LoginAttemptStore.Attempt(ctx, ipKey, window, repo.LoginLimit{
+		MaxFailures: u.AuthCfg.LoginMaxIPFailures,
+		Lockout:     u.AuthCfg.LoginLockout,
+	})
+	switch {
+	case err != nil:
+		code = http.StatusInternalServerError
+	case wait > 0:
+		code, err = http.StatusTooManyRequests, &RetryAfterError{RetryAfter: wait}
+	default:
+		return http.StatusOK, nil
+	}
+
+	// the attempt never reached the password check, so it must not count
+	// against the account either
+	if forgiveErr := u.LoginAttemptStore.Forgive(ctx, accountKey); forgiveErr != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][attemptLogin][Forgive] err : %v", forgiveErr))
+	}
+	return code, err
+}
+
+// loginWindow keeps failures at least as long as a lockout lasts.
+func (u *UserSvcImpl) loginWindow() time.Duration {
+	if u.AuthCfg.LoginLockout > u.AuthCfg.LoginWindow {
+		return u.AuthCfg.LoginLockout
+	}
+	return u.AuthCfg.LoginWindow
+}
+
+// UnlockUser clears the failed login count of a locked account.
+func (u *UserSvcImpl) UnlockUser(ctx context.Context, id int) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusBadGateway
+		resp.Message = "Failed to unlock user"
+	}
+
+	_, err = u.UserRepo.GetUserByID(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UnlockUser][GetUserByID] err : %v", err))
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			resp.Code = http.StatusNotFound
+			resp.Message = "User not found"
+		}
+		return
+	}
+
+	err = u.LoginAttemptStore.Reset(ctx, fmt.Sprintf("user:%d", id))
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UnlockUser][Reset] err : %v", err))
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "User unlocked successfully"
+	return
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Each refresh
+// token works once; presenting one that was already exchanged means it has
+// leaked, so every token descended from the same login is revoked.
+func (u *UserSvcImpl) RefreshToken(ctx context.Context, req RefreshTokenReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][RefreshToken][GenerateOpaqueToken] err : %v", err))
+		return
+	}
+
+	refreshExp := time.Now().Add(u.JwtCfg.RefreshTokenTTL)
+	current, err := u.RefreshTokenRepo.RotateRefreshToken(ctx, utils.HashToken(req.RefreshToken), models.RefreshToken{
+		TokenHash: refreshHash,
+		ExpiresAt: refreshExp,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][RefreshToken][RotateRefreshToken] err : %v", err))
+		switch {
+		case errors.Is(err, postgres.ErrRefreshTokenReused):
+			if revokeErr := u.RefreshTokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID); revokeErr != nil {
+				slog.ErrorContext(ctx, fmt.Sprintf("[service][RefreshToken][RevokeRefreshTokenFamily] err : %v", revokeErr))
+				return
+			}
+			fallthrough
+		case errors.Is(err, postgres.ErrRefreshTokenNotFound), errors.Is(err, postgres.ErrRefreshTokenExpired):
+			resp.Code = http.StatusUnauthorized
+			resp.Message = "invalid refresh token"
+			resp.Error = err.Error()
+		}
+		return
+	}
+
+	user, err := u.UserRepo.GetUserByID(ctx, current.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][RefreshToken][GetUserByID] err : %v", err))
+		return
+	}
+
+	tokens, err := u.signTokens(user, refreshToken, refreshExp)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][RefreshToken][Sign] err : %v", err))
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "success"
+	resp.Data = tokens
+	return
+}
+
+// Logout revokes the access token used for the request and, when given, the
+// refresh token issued with it.
+func (u *UserSvcImpl) Logout(ctx context.Context, req LogoutReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+	}
+
+	userCtx := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+
+	err = u.RevocationStore.RevokeToken(ctx, userCtx.TokenID, userCtx.UserID, userCtx.TokenExpiresAt)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][Logout][RevokeToken] err : %v", err))
+		return
+	}
+
+	if req.RefreshToken != "" {
+		err = u.RefreshTokenRepo.RevokeRefreshToken(ctx, userCtx.UserID, utils.HashToken(req.RefreshToken))
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("[service][Logout][RevokeRefreshToken] err : %v", err))
+			return
+		}
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "successfully logged out"
+	return
+}
+
+func (u *UserSvcImpl) LogoutAll(ctx context.Context) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+	}
+
+	userCtx := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+
+	err = u.revokeAllSessions(ctx, userCtx.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][LogoutAll][revokeAllSessions] err : %v", err))
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "successfully logged out from all sessions"
+	return
+}
+
+// GetProfile answers from the user AuthUser already loaded for the request.
+func (u *UserSvcImpl) GetProfile(ctx context.Context) (resp models.DefaultResponse, err error) {
+	userCtx := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+
+	resp.Code = http.StatusOK
+	resp.Message = "success"
+	resp.Data = profileOf(userCtx)
+	return
+}
+
+func (u *UserSvcImpl) UpdateProfile(ctx context.Context, req UpdateProfileReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+		req.Username = strings.ToLower(strings.TrimSpace(req.Username))
+	}
+
+	userCtx := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+
+	err = u.UserRepo.UpdateUsername(ctx, userCtx.UserID, req.Username)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UpdateProfile][UpdateUsername] err : %v", err))
+		if errors.Is(err, postgres.ErrUsernameTaken) {
+			resp.Code = http.StatusConflict
+			resp.Message = "username is already taken"
+			resp.Error = err.Error()
+		}
+		return
+	}
+
+	userCtx.Username = req.Username
+	resp.Code = http.StatusOK
+	resp.Message = "profile updated"
+	resp.Data = profileOf(userCtx)
+	return
+}
+
+// ChangePassword replaces the password after checking the current one, then
+// signs the user out everywhere, including the session making the request.
+func (u *UserSvcImpl) ChangePassword(ctx context.Context, req ChangePasswordReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+	}
+
+	userCtx := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+
+	err = u.checkCurrentPassword(ctx, userCtx.UserID, req.CurrentPassword)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangePassword][checkCurrentPassword] err : %v", err))
+		if errors.Is(err, ErrWrongPassword) {
+			resp.Code = http.StatusForbidden
+			resp.Message = "current password is incorrect"
+			resp.Error = err.Error()
+		}
+		return
+	}
+
+	password, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangePassword][HashPassword] err : %v", err))
+		return
+	}
+
+	err = u.UserRepo.UpdatePassword(ctx, userCtx.UserID, password)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangePassword][UpdatePassword] err : %v", err))
+		return
+	}
+
+	err = u.revokeAllSessions(ctx, userCtx.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangePassword][revokeAllSessions] err : %v", err))
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "password changed, please sign in again"
+	return
+}
+
+// ChangeEmail mails a confirmation link to the new address. The account keeps
+// its current email until the link is opened.
+func (u *UserSvcImpl) ChangeEmail(ctx context.Context, req ChangeEmailReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+		req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	}
+
+	userCtx := ctx.Value(middleware.UserData).(middleware.UserCtxReq)
+
+	err = u.checkCurrentPassword(ctx, userCtx.UserID, req.Password)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangeEmail][checkCurrentPassword] err : %v", err))
+		if errors.Is(err, ErrWrongPassword) {
+			resp.Code = http.StatusForbidden
+			resp.Message = "current password is incorrect"
+			resp.Error = err.Error()
+		}
+		return
+	}
+
+	if req.Email == userCtx.Email {
+		resp.Code = http.StatusBadRequest
+		resp.Message = "new email is the same as the current one"
+		return
+	}
+
+	_, err = u.UserRepo.GetUserByEmail(ctx, req.Email)
+	if err == nil {
+		err = postgres.ErrEmailTaken
+		resp.Code = http.StatusConflict
+		resp.Message = "email is already registered"
+		resp.Error = err.Error()
+		return
+	}
+
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangeEmail][GenerateOpaqueToken] err : %v", err))
+		return
+	}
+
+	_, err = u.UserTokenRepo.CreateUserToken(ctx, models.UserToken{
+		UserID:    userCtx.UserID,
+		Purpose:   models.TokenPurposeEmailChange,
+		TokenHash: tokenHash,
+		NewEmail:  &req.Email,
+		ExpiresAt: time.Now().Add(u.AuthCfg.EmailVerificationTTL),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangeEmail][CreateUserToken] err : %v", err))
+		return
+	}
+
+	err = u.Mailer.Send(ctx, mailer.Message{
+		To:      req.Email,
+		Subject: "Confirm your new be-shop email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nOpen the link below to use this address for your be-shop account. It expires in %s.\n\n%s\n",
+			userCtx.Username, u.AuthCfg.EmailVerificationTTL, tokenLink(u.AuthCfg.EmailChangeURL, token),
+		),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangeEmail][Send] err : %v", err))
+		return
+	}
+
+	// let the current address know, in case the change is not the owner's
+	if notifyErr := u.Mailer.Send(ctx, mailer.Message{
+		To:      userCtx.Email,
+		Subject: "Your be-shop email address is being changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to move your be-shop account to %s. If this was not you, change your password now.\n",
+			userCtx.Username, req.Email,
+		),
+	}); notifyErr != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ChangeEmail][Send] notify err : %v", notifyErr))
+	}
+
+	resp.Code = http.StatusAccepted
+	resp.Message = "a confirmation link has been sent to the new email address"
+	return
+}
+
+func (u *UserSvcImpl) ConfirmEmailChange(ctx context.Context, req VerifyEmailReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+	}
+
+	_, err = u.UserRepo.ConfirmEmailChange(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ConfirmEmailChange][ConfirmEmailChange] err : %v", err))
+		switch {
+		case errors.Is(err, postgres.ErrUserTokenInvalid):
+			resp.Code = http.StatusBadRequest
+			resp.Message = "invalid or expired confirmation token"
+			resp.Error = err.Error()
+		case errors.Is(err, postgres.ErrEmailTaken):
+			resp.Code = http.StatusConflict
+			resp.Message = "email is already registered"
+			resp.Error = err.Error()
+		}
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "email address has been changed"
+	return
+}
+
+// checkCurrentPassword returns ErrWrongPassword when password is not the
+// user's current password.
+func (u *UserSvcImpl) checkCurrentPassword(ctx context.Context, userID int, password string) (err error) {
+	user, err := u.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	if utils.VerifyPassword(password, user.Password) != nil {
+		return ErrWrongPassword
+	}
+	return
+}
+
+func profileOf(userCtx middleware.UserCtxReq) models.Profile {
+	return models.Profile{
+		ID:            userCtx.UserID,
+		Email:         userCtx.Email,
+		Username:      userCtx.Username,
+		Role:          userCtx.Role,
+		EmailVerified: userCtx.EmailVerified,
+	}
+}
+
+// ForgotPassword mails a password reset link. It answers the same way whether
+// or not the email belongs to an account, so it cannot be used to find out
+// which addresses are registered. The token and mail are created in the
+// background, so a known address does not answer measurably slower either.
+func (u *UserSvcImpl) ForgotPassword(ctx context.Context, req ForgotPasswordReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusOK
+		resp.Message = "if the email is registered, a password reset link has been sent"
+		req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	}
+
+	user, err := u.UserRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ForgotPassword][GetUserByEmail] err : %v", err))
+		return
+	}
+
+	go u.sendPasswordReset(user)
+	return
+}
+
+// sendPasswordReset issues a reset token for user and mails it. It runs
+// detached from the request that asked for it, so failures are only logged.
+func (u *UserSvcImpl) sendPasswordReset(user models.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundMailTimeout)
+	defer cancel()
+
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ForgotPassword][GenerateOpaqueToken] err : %v", err))
+		return
+	}
+
+	_, err = u.UserTokenRepo.CreateUserToken(ctx, models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposePasswordReset,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(u.AuthCfg.PasswordResetTTL),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ForgotPassword][CreateUserToken] err : %v", err))
+		return
+	}
+
+	err = u.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your be-shop password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.Username, u.AuthCfg.PasswordResetTTL, tokenLink(u.AuthCfg.PasswordResetURL, token),
+		),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ForgotPassword][Send] err : %v", err))
+	}
+}
+
+// ResetPassword sets a new password from a reset token and signs the user out
+// everywhere.
+func (u *UserSvcImpl) ResetPassword(ctx context.Context, req ResetPasswordReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusInternalServerError
+		resp.Message = "internal server error, we will fix it soon"
+	}
+
+	password, err := utils.HashPassword(req.Password)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ResetPassword][HashPassword] err : %v", err))
+		return
+	}
+
+	userID, err := u.UserRepo.ResetPassword(ctx, utils.HashToken(req.Token), password)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ResetPassword][ResetPassword] err : %v", err))
+		if errors.Is(err, postgres.ErrUserTokenInvalid) {
+			resp.Code = http.StatusBadRequest
+			resp.Message = "invalid or expired reset token"
+			resp.Error = err.Error()
+		}
+		return
+	}
+
+	err = u.revokeAllSessions(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][ResetPassword][revokeAllSessions] err : %v", err))
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "password has been reset"
+	return
+}
+
+// tokenLink appends token to base as the token query parameter.
+func tokenLink(base, token string) string {
+	link, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// revokeAllSessions invalidates every access and refresh token the user holds.
+// It must be called whenever the user's password changes.
+func (u *UserSvcImpl) revokeAllSessions(ctx context.Context, userID int) (err error) {
+	// iat only has second precision; tokens issued later in the current
+	// second stay valid so the user can sign in again right away
+	err = u.RevocationStore.RevokeUser(ctx, userID, time.Now().Truncate(time.Second))
+	if err != nil {
+		return
+	}
+	return u.RefreshTokenRepo.RevokeUserRefreshTokens(ctx, userID)
+}
+
+func (u *UserSvcImpl) signTokens(user models.User, refreshToken string, refreshExp time.Time) (tokens TokenPair, err error) {
+	token, exp, err := u.KeyManager.Sign(JWTData{
+		Email:   user.Email,
+		UserID:  user.ID,
+		Usename: user.Username,
+	}, u.JwtCfg.AccessTokenTTL)
+	if err != nil {
+		return
+	}
+
+	tokens = TokenPair{
+		Token:         token,
+		Expire:        exp,
+		RefreshToken:  refreshToken,
+		RefreshExpire: refreshExp.Format(time.RFC3339),
+	}
+	return
+}
+
+func (u *UserSvcImpl) UpdateUserRole(ctx context.Context, id int, req UpdateRoleReq) (resp models.DefaultResponse, err error) {
+	{
+		resp.Code = http.StatusBadGateway
+		resp.Message = "Failed to update user role"
+	}
+
+	err = u.UserRepo.UpdateUserRole(ctx, id, req.Role)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[service][UpdateUserRole] err : %v", err))
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			resp.Code = http.StatusNotFound
+			resp.Message = "User not found"
+		}
+		return
+	}
+
+	resp.Code = http.StatusOK
+	resp.Message = "User role updated successfully"
 	resp.Data = struct {
-		Token  string `json:"token"`
-		Expire string `json:"expire"`
+		ID   int         `json:"id"`
+		Role models.Role `json:"role"`
 	}{
-		Token:  token,
-		Expire: exp,
+		ID:   id,
+		Role: req.Role,
 	}
-
 	return
 }
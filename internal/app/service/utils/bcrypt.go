@@ -21,6 +21,15 @@ func HashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
+// dummyPasswordHash is checked against when a login names no known user, so
+// the request costs as much as a real password check.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("be-shop-dummy-password"), bcrypt.DefaultCost)
+
+// VerifyDummyPassword spends the time of VerifyPassword without a real hash.
+func VerifyDummyPassword(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
 func VerifyPassword(password, hashedPassword string) error {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	if err != nil {
@@ -5,7 +5,9 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -79,3 +81,21 @@ func RandomString(n int) string {
 	}
 	return string(b)
 }
+
+// GenerateOpaqueToken returns a random URL-safe token together with the hash
+// that should be stored in its place.
+func GenerateOpaqueToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	hash = HashToken(token)
+	return
+}
+
+// HashToken returns the hex encoded SHA-256 of token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,62 +1,289 @@
 package utils
 
 import (
+	"be-shop/internal/app/infra"
+	"crypto"
+	"crypto/aes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
+// defaultKeyID names the HS256 key built from JWT_SECRET_KEY when no
+// asymmetric keys are configured.
+const defaultKeyID = "default"
+
+var (
+	ErrUnknownKeyID     = errors.New("jwt: unknown or retired key id")
+	ErrUnexpectedMethod = errors.New("jwt: unexpected signing method")
+	ErrInvalidIssuer    = errors.New("jwt: invalid issuer")
+	ErrMissingExpiry    = errors.New("jwt: token has no expiry")
+	ErrWeakSecret       = errors.New("jwt: JWT_SECRET_KEY is the sample value or shorter than 32 bytes")
+)
+
 type (
+	// Claims carries the encrypted user data. Every token gets a unique jti
+	// (StandardClaims.Id) and an iat so it can be revoked before it expires.
 	Claims struct {
 		Data any `json:"data"`
 		jwt.StandardClaims
 	}
+
+	// KeyManager signs access tokens with the active key and verifies them
+	// with any key that is still valid. A key stops signing when it is
+	// retired but keeps verifying for the rotation overlap, so tokens issued
+	// just before a rotation survive until they expire.
+	KeyManager struct {
+		keys     map[string]*signingKey
+		activeID string
+		overlap  time.Duration
+		issuer   string
+		encKey   string
+		encIV    string
+	}
+
+	signingKey struct {
+		id        string
+		method    jwt.SigningMethod
+		private   crypto.PrivateKey
+		public    crypto.PublicKey
+		retiredAt *time.Time
+	}
+
+	// JWK is a public key in JSON Web Key format (RFC 7517).
+	JWK struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+	}
+
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
 )
 
-func Sign(data any) (signatureJWT string, exp string, err error) {
-	secret := []byte(os.Getenv("JWT_SECRET_KEY"))
+// NewKeyManager loads the keys listed in cfg. Without JWT_KEYS it falls back
+// to a single HS256 key built from JWT_SECRET_KEY, which is not published in
+// the JWKS and is refused when it is the sample default or too short to
+// resist guessing.
+func NewKeyManager(cfg *infra.JwtCfg) (*KeyManager, error) {
+	km := &KeyManager{
+		keys:     make(map[string]*signingKey),
+		activeID: cfg.ActiveKeyID,
+		overlap:  cfg.RotationOverlap,
+		issuer:   cfg.Issuer,
+		encKey:   cfg.EncryptKey,
+		encIV:    cfg.EncryptIV,
+	}
+	switch len(km.encKey) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.New("jwt: JWT_ENCRYPT_KEY must be 16, 24 or 32 bytes")
+	}
+	if len(km.encIV) != aes.BlockSize {
+		return nil, errors.New("jwt: JWT_ENCRYPT_IV must be 16 bytes")
+	}
+	// a retired key must outlive every token it signed
+	if km.overlap < cfg.AccessTokenTTL {
+		km.overlap = cfg.AccessTokenTTL
+	}
+
+	if len(cfg.Keys) == 0 {
+		if cfg.SecretKey == infra.SampleJwtSecretKey || len(cfg.SecretKey) < infra.MinJwtSecretLength {
+			return nil, ErrWeakSecret
+		}
+		km.activeID = defaultKeyID
+		km.keys[defaultKeyID] = &signingKey{
+			id:      defaultKeyID,
+			method:  jwt.SigningMethodHS256,
+			private: []byte(cfg.SecretKey),
+			public:  []byte(cfg.SecretKey),
+		}
+		return km, nil
+	}
+
+	for id, path := range cfg.Keys {
+		key, err := loadSigningKey(id, path)
+		if err != nil {
+			return nil, err
+		}
+		km.keys[id] = key
+	}
+
+	for id, value := range cfg.RetiredKeys {
+		key, ok := km.keys[id]
+		if !ok {
+			return nil, fmt.Errorf("jwt: retired key %q is not listed in JWT_KEYS", id)
+		}
+		retiredAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: retired key %q: %w", id, err)
+		}
+		key.retiredAt = &retiredAt
+	}
+
+	active, ok := km.keys[km.activeID]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("jwt: active key %q is not listed in JWT_KEYS", km.activeID)
+	case active.private == nil:
+		return nil, fmt.Errorf("jwt: active key %q has no private key", km.activeID)
+	case active.retiredAt != nil:
+		return nil, fmt.Errorf("jwt: active key %q is retired", km.activeID)
+	}
+
+	return km, nil
+}
+
+// loadSigningKey reads a PEM file holding an RSA or Ed25519 key. A public key
+// on its own can only verify, which is enough for keys being rotated out.
+func loadSigningKey(id, path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: key %q: %w", id, err)
+	}
+
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return &signingKey{id: id, method: jwt.SigningMethodRS256, private: private, public: &private.PublicKey}, nil
+	}
+	if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		edKey, ok := private.(ed25519.PrivateKey)
+		if ok {
+			return &signingKey{id: id, method: jwt.SigningMethodEdDSA, private: edKey, public: edKey.Public()}, nil
+		}
+	}
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &signingKey{id: id, method: jwt.SigningMethodRS256, public: public}, nil
+	}
+	if public, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return &signingKey{id: id, method: jwt.SigningMethodEdDSA, public: public}, nil
+	}
+
+	return nil, fmt.Errorf("jwt: key %q: not an RSA or Ed25519 PEM key", id)
+}
+
+// usable reports whether the key may still verify tokens at now.
+func (k *signingKey) usable(now time.Time, overlap time.Duration) bool {
+	return k.retiredAt == nil || now.Before(k.retiredAt.Add(overlap))
+}
+
+func (km *KeyManager) Sign(data any, ttl time.Duration) (signatureJWT string, exp string, err error) {
+	key := km.keys[km.activeID]
+
 	bt, err := json.Marshal(data)
 	if err != nil {
 		return
 	}
-	exp = time.Now().Add(7 * 24 * time.Hour).Format(time.RFC3339)
-	encryptedData, err := EncryptAES256CBC(string(bt), os.Getenv("JWT_ENCRYPT_KEY"), os.Getenv("JWT_ENCRYPT_IV"))
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	exp = expiresAt.Format(time.RFC3339)
+	encryptedData, err := EncryptAES256CBC(string(bt), km.encKey, km.encIV)
 	if err != nil {
 		return
 	}
 	claims := &Claims{
 		encryptedData,
 		jwt.StandardClaims{
-			Issuer:    os.Getenv("JWT_ISSUER"),
-			ExpiresAt: time.Now().Add(7 * 24 * time.Hour).Unix(),
+			Issuer:    km.issuer,
+			ExpiresAt: expiresAt.Unix(),
+			IssuedAt:  now.Unix(),
+			Id:        RandomString(32),
 		},
 	}
-	signatureJWT, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	signatureJWT, err = token.SignedString(key.private)
 	return
 }
 
-func Verify(token string) (claims *Claims, err error) {
-	secret := []byte(os.Getenv("JWT_SECRET_KEY"))
+// Verify checks the token against the key named by its kid header. The
+// token's alg must match that key's algorithm exactly, so a token cannot pick
+// a weaker method or use a public key as an HMAC secret.
+func (km *KeyManager) Verify(token string) (claims *Claims, err error) {
 	claims = &Claims{}
 	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-		return secret, nil
+		kid, _ := t.Header["kid"].(string)
+		key, ok := km.keys[kid]
+		if !ok || !key.usable(time.Now(), km.overlap) {
+			return nil, ErrUnknownKeyID
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, ErrUnexpectedMethod
+		}
+		return key.public, nil
 	})
 	if err != nil {
 		return
 	}
-	encryptedString, err := DecryptAES256CBC(claims.Data.(string), os.Getenv("JWT_ENCRYPT_KEY"), os.Getenv("JWT_ENCRYPT_IV")) // * should be string
+	if claims.ExpiresAt == 0 {
+		return claims, ErrMissingExpiry
+	}
+	if km.issuer != "" && claims.Issuer != km.issuer {
+		return claims, ErrInvalidIssuer
+	}
+
+	data, ok := claims.Data.(string)
+	if !ok {
+		return claims, errors.New("jwt: invalid data claim")
+	}
+	encryptedString, err := DecryptAES256CBC(data, km.encKey, km.encIV)
 	if err != nil {
 		return
 	}
-	var data any
-	err = json.Unmarshal([]byte(encryptedString), &data)
+	var decoded any
+	err = json.Unmarshal([]byte(encryptedString), &decoded)
 	if err != nil {
 		return
 	}
-	claims.Data = data
+	claims.Data = decoded
 
 	return
+}
 
+// JWKS returns the public keys that currently verify tokens, including keys
+// not yet active so other services can cache them ahead of a rotation.
+func (km *KeyManager) JWKS() JWKS {
+	now := time.Now()
+	set := JWKS{Keys: make([]JWK, 0, len(km.keys))}
+	for _, key := range km.keys {
+		if !key.usable(now, km.overlap) {
+			continue
+		}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.id,
+				Alg: key.method.Alg(),
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.id,
+				Alg: key.method.Alg(),
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
 }
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"be-shop/internal/app/infra"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testEncryptKey = "0123456789abcdef"
+	testEncryptIV  = "fedcba9876543210"
+)
+
+// writeTestKeys writes an RSA key as "rsa", an Ed25519 key as "ed" and the
+// RSA public key alone as "rsa-public", returning their paths by id.
+func writeTestKeys(t *testing.T) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+
+	blocks := map[string]*pem.Block{
+		"rsa":        {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"ed":         {Type: "PRIVATE KEY", Bytes: edDER},
+		"rsa-public": {Type: "PUBLIC KEY", Bytes: publicDER},
+	}
+	paths := make(map[string]string, len(blocks))
+	for id, block := range blocks {
+		path := filepath.Join(dir, id+".pem")
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		paths[id] = path
+	}
+	return paths
+}
+
+func testJwtCfg(keys map[string]string, active string) *infra.JwtCfg {
+	return &infra.JwtCfg{
+		EncryptKey:      testEncryptKey,
+		EncryptIV:       testEncryptIV,
+		Issuer:          "be-shop",
+		AccessTokenTTL:  15 * time.Minute,
+		Keys:            keys,
+		ActiveKeyID:     active,
+		RotationOverlap: time.Hour,
+	}
+}
+
+func newTestKeyManager(t *testing.T, cfg *infra.JwtCfg) *KeyManager {
+	t.Helper()
+	km, err := NewKeyManager(cfg)
+	if err != nil {
+		t.Fatalf("NewKeyManager: %v", err)
+	}
+	return km
+}
+
+// verifyError unwraps the error jwt.ParseWithClaims reports for a rejected
+// key.
+func verifyError(err error) error {
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Inner != nil {
+		return validationErr.Inner
+	}
+	return err
+}
+
+func TestNewKeyManagerSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr error
+	}{
+		{name: "sample default", secret: infra.SampleJwtSecretKey, wantErr: ErrWeakSecret},
+		{name: "empty", secret: "", wantErr: ErrWeakSecret},
+		{name: "too short", secret: strings.Repeat("x", infra.MinJwtSecretLength-1), wantErr: ErrWeakSecret},
+		{name: "long enough", secret: strings.Repeat("x", infra.MinJwtSecretLength)},
+	}
+
+	for _, tt := range tests {
+		cfg := testJwtCfg(nil, "")
+		cfg.SecretKey = tt.secret
+		if _, err := NewKeyManager(cfg); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: NewKeyManager error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// the secret is not used, and so not checked, once asymmetric keys are
+	// configured
+	cfg := testJwtCfg(map[string]string{"ed": writeTestKeys(t)["ed"]}, "ed")
+	cfg.SecretKey = infra.SampleJwtSecretKey
+	if _, err := NewKeyManager(cfg); err != nil {
+		t.Errorf("NewKeyManager with JWT_KEYS: %v", err)
+	}
+}
+
+func TestKeyManagerSignsWithActiveKid(t *testing.T) {
+	paths := writeTestKeys(t)
+
+	for _, tt := range []struct {
+		active string
+		alg    string
+	}{
+		{active: "rsa", alg: "RS256"},
+		{active: "ed", alg: "EdDSA"},
+	} {
+		km := newTestKeyManager(t, testJwtCfg(map[string]string{"rsa": paths["rsa"], "ed": paths["ed"]}, tt.active))
+
+		token, _, err := km.Sign(map[string]any{"user_id": 1}, time.Minute)
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", tt.active, err)
+		}
+		parsed, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+		if err != nil {
+			t.Fatalf("%s: ParseUnverified: %v", tt.active, err)
+		}
+		if parsed.Header["kid"] != tt.active || parsed.Header["alg"] != tt.alg {
+			t.Errorf("%s: header = %v, want kid %s alg %s", tt.active, parsed.Header, tt.active, tt.alg)
+		}
+
+		claims, err := km.Verify(token)
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", tt.active, err)
+		}
+		if data, _ := claims.Data.(map[string]any); data["user_id"] != float64(1) {
+			t.Errorf("%s: data = %v", tt.active, claims.Data)
+		}
+	}
+}
+
+func TestKeyManagerRejectsUnknownKid(t *testing.T) {
+	paths := writeTestKeys(t)
+	signer := newTestKeyManager(t, testJwtCfg(map[string]string{"ed": paths["ed"]}, "ed"))
+	verifier := newTestKeyManager(t, testJwtCfg(map[string]string{"rsa": paths["rsa"]}, "rsa"))
+
+	token, _, err := signer.Sign("data", time.Minute)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := verifier.Verify(token); !errors.Is(verifyError(err), ErrUnknownKeyID) {
+		t.Errorf("Verify with unknown kid error = %v, want ErrUnknownKeyID", err)
+	}
+}
+
+func TestKeyManagerRetiredKeyOverlap(t *testing.T) {
+	paths := writeTestKeys(t)
+	keys := map[string]string{"rsa": paths["rsa"], "ed": paths["ed"]}
+
+	old := newTestKeyManager(t, testJwtCfg(keys, "rsa"))
+	token, _, err := old.Sign("data", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		retiredAt time.Time
+		overlap   time.Duration
+		wantErr   error
+	}{
+		{name: "within overlap", retiredAt: time.Now().Add(-30 * time.Minute), overlap: time.Hour},
+		{name: "past overlap", retiredAt: time.Now().Add(-2 * time.Hour), overlap: time.Hour, wantErr: ErrUnknownKeyID},
+		// the overlap is stretched to the access token TTL
+		{name: "overlap shorter than ttl", retiredAt: time.Now().Add(-10 * time.Minute), overlap: time.Minute},
+	}
+
+	for _, tt := range tests {
+		cfg := testJwtCfg(keys, "ed")
+		cfg.RotationOverlap = tt.overlap
+		cfg.RetiredKeys = infra.KeyTimes{"rsa": tt.retiredAt.UTC().Format(time.RFC3339)}
+		km := newTestKeyManager(t, cfg)
+
+		if _, err := km.Verify(token); !errors.Is(verifyError(err), tt.wantErr) {
+			t.Errorf("%s: Verify error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if fresh, _, err := km.Sign("data", time.Minute); err != nil || kidOf(t, fresh) != "ed" {
+			t.Errorf("%s: new tokens not signed with the active key: %v", tt.name, err)
+		}
+	}
+
+	cfg := testJwtCfg(keys, "rsa")
+	cfg.RetiredKeys = infra.KeyTimes{"rsa": time.Now().UTC().Format(time.RFC3339)}
+	if _, err := NewKeyManager(cfg); err == nil {
+		t.Errorf("NewKeyManager accepted a retired active key")
+	}
+}
+
+func TestKeyManagerRejectsAlgMismatch(t *testing.T) {
+	paths := writeTestKeys(t)
+	km := newTestKeyManager(t, testJwtCfg(map[string]string{"rsa": paths["rsa"], "ed": paths["ed"], "rsa-public": paths["rsa-public"]}, "ed"))
+
+	publicPEM, err := os.ReadFile(paths["rsa-public"])
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		kid    string
+		key    interface{}
+	}{
+		// the public key, which anyone can fetch, used as an HMAC secret
+		{name: "HS256 with the RSA public key", method: jwt.SigningMethodHS256, kid: "rsa-public", key: publicPEM},
+		{name: "HS256 under an RSA kid", method: jwt.SigningMethodHS256, kid: "rsa", key: publicPEM},
+		{name: "EdDSA under an RSA kid", method: jwt.SigningMethodEdDSA, kid: "rsa", key: edKey},
+	}
+
+	for _, tt := range tests {
+		token := jwt.NewWithClaims(tt.method, &Claims{Data: "data", StandardClaims: jwt.StandardClaims{
+			Issuer:    "be-shop",
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		}})
+		token.Header["kid"] = tt.kid
+		signed, err := token.SignedString(tt.key)
+		if err != nil {
+			t.Fatalf("%s: SignedString: %v", tt.name, err)
+		}
+		if _, err := km.Verify(signed); !errors.Is(verifyError(err), ErrUnexpectedMethod) {
+			t.Errorf("%s: Verify error = %v, want ErrUnexpectedMethod", tt.name, err)
+		}
+	}
+
+	if _, err := NewKeyManager(testJwtCfg(map[string]string{"rsa-public": paths["rsa-public"]}, "rsa-public")); err == nil {
+		t.Errorf("NewKeyManager accepted a public-only active key")
+	}
+}
+
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
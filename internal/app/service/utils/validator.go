@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"be-shop/pkg/money"
+	"reflect"
 	"regexp"
 
 	"github.com/go-playground/validator/v10"
@@ -22,6 +24,14 @@ func hasSpecialChar(fl validator.FieldLevel) bool {
 	return regexp.MustCompile(`[^a-zA-Z0-9]`).MatchString(fl.Field().String())
 }
 
+// moneyAmount lets numeric tags such as gt=0 apply to a money.Money's amount.
+func moneyAmount(field reflect.Value) interface{} {
+	if m, ok := field.Interface().(money.Money); ok {
+		return m.Amount
+	}
+	return nil
+}
+
 var Validate *validator.Validate
 
 func InitValidator() {
@@ -31,4 +41,6 @@ func InitValidator() {
 	Validate.RegisterValidation("lowercase", hasLowercase)
 	Validate.RegisterValidation("number", hasNumber)
 	Validate.RegisterValidation("specialchar", hasSpecialChar)
+
+	Validate.RegisterCustomTypeFunc(moneyAmount, money.Money{})
 }
\ No newline at end of file
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"be-shop/internal/app/models"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotent makes a handler safe to retry. The first response for a user's
+// Idempotency-Key on an endpoint is stored and replayed for later requests
+// with the same key and body, for as long as IdempotencyCfg.Retention. A key
+// whose request never finished is freed after IdempotencyCfg.InFlightTimeout.
+// It must run after AuthUser. Requests without the header pass straight
+// through.
+func (m *MiddleWareImpl) Idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return next(c)
+		}
+
+		ctx := c.Request().Context()
+		errResponse := models.DefaultResponse{Code: http.StatusBadRequest}
+
+		if len(key) > maxIdempotencyKeyLength {
+			errResponse.Message = "Idempotency-Key is too long"
+			return c.JSON(http.StatusBadRequest, errResponse)
+		}
+
+		userData, ok := ctx.Value(UserData).(UserCtxReq)
+		if !ok {
+			errResponse.Code = http.StatusUnauthorized
+			errResponse.Message = "Unauthorized"
+			return c.JSON(http.StatusUnauthorized, errResponse)
+		}
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			errResponse.Message = "Invalid request body"
+			errResponse.Error = err.Error()
+			return c.JSON(http.StatusBadRequest, errResponse)
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+		record, reserved, err := m.IdempotencyRepo.Reserve(ctx, models.IdempotencyKey{
+			UserID:      userData.UserID,
+			Key:         key,
+			Endpoint:    c.Request().Method + " " + c.Path(),
+			RequestHash: hex.EncodeToString(hash[:]),
+		}, m.IdempotencyCfg.Retention, m.IdempotencyCfg.InFlightTimeout)
+		if err != nil {
+			errResponse.Code = http.StatusBadGateway
+			errResponse.Message = "Failed to process Idempotency-Key"
+			return c.JSON(http.StatusBadGateway, errResponse)
+		}
+
+		if !reserved {
+			switch {
+			case record.RequestHash != hex.EncodeToString(hash[:]):
+				errResponse.Code = http.StatusUnprocessableEntity
+				errResponse.Message = "Idempotency-Key was already used with a different request body"
+				return c.JSON(http.StatusUnprocessableEntity, errResponse)
+			case record.ResponseCode == nil:
+				errResponse.Code = http.StatusConflict
+				errResponse.Message = "A request with this Idempotency-Key is still being processed"
+				return c.JSON(http.StatusConflict, errResponse)
+			}
+
+			c.Response().Header().Set(IdempotentReplayedHeader, "true")
+			return c.Blob(*record.ResponseCode, echo.MIMEApplicationJSONCharsetUTF8, record.ResponseBody)
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+
+		err = next(c)
+
+		// failures we may not have acted on are forgotten so the client can
+		// retry with the same key
+		status := c.Response().Status
+		if err != nil || status >= http.StatusInternalServerError {
+			if releaseErr := m.IdempotencyRepo.Release(ctx, record.ID); releaseErr != nil {
+				slog.ErrorContext(ctx, "[MiddleWare.Idempotent] error while Release err", "%v", releaseErr.Error())
+			}
+			return err
+		}
+
+		if completeErr := m.IdempotencyRepo.Complete(ctx, record.ID, status, recorder.body.Bytes()); completeErr != nil {
+			slog.ErrorContext(ctx, "[MiddleWare.Idempotent] error while Complete err", "%v", completeErr.Error())
+		}
+		return nil
+	}
+}
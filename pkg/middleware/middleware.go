@@ -1,13 +1,16 @@
 package middleware
 
 import (
+	"be-shop/internal/app/infra"
 	"be-shop/internal/app/models"
+	"be-shop/internal/app/repo"
 	"be-shop/internal/app/repo/postgres"
 	"be-shop/internal/app/service/utils"
 	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/dig"
@@ -19,19 +22,33 @@ const (
 
 type (
 	UserCtxReq struct {
-		UserID   int    `json:"user_id"`
-		Email    string `json:"email"`
-		Username string `json:"username"`
+		UserID   int         `json:"user_id"`
+		Email    string      `json:"email"`
+		Username string      `json:"username"`
+		Role     models.Role `json:"role"`
+
+		EmailVerified bool `json:"-"`
+
+		// TokenID and TokenExpiresAt identify the access token used for the
+		// request so it can be revoked on logout.
+		TokenID        string    `json:"-"`
+		TokenExpiresAt time.Time `json:"-"`
 	}
 
 	MiddleWareImpl struct {
 		dig.In
 
-		UserRepo postgres.UserRepo
+		UserRepo        postgres.UserRepo
+		RevocationStore repo.RevocationStore
+		KeyManager      *utils.KeyManager
+		IdempotencyRepo postgres.IdempotencyRepo
+		IdempotencyCfg  *infra.IdempotencyCfg
 	}
 
 	MiddleWare interface {
 		AuthUser(next echo.HandlerFunc) echo.HandlerFunc
+		Idempotent(next echo.HandlerFunc) echo.HandlerFunc
+		RequireRole(roles ...models.Role) echo.MiddlewareFunc
 	}
 
 	userDataKey string
@@ -52,12 +69,17 @@ func (m *MiddleWareImpl) AuthUser(next echo.HandlerFunc) echo.HandlerFunc {
 		}
 
 		token = strings.Replace(token, "Bearer ", "", 1)
-		userData, err := utils.Verify(token)
+		userData, err := m.KeyManager.Verify(token)
 		if err != nil {
 			errResponse.Message = err.Error()
 			return c.JSON(http.StatusUnauthorized, errResponse)
 		}
 
+		if userData.Id == "" {
+			errResponse.Message = "Token is no longer valid"
+			return c.JSON(http.StatusUnauthorized, errResponse)
+		}
+
 		var userCtx UserCtxReq
 		bt, err := json.Marshal(userData.Data)
 		if err != nil {
@@ -73,6 +95,17 @@ func (m *MiddleWareImpl) AuthUser(next echo.HandlerFunc) echo.HandlerFunc {
 			return c.JSON(http.StatusUnauthorized, errResponse)
 		}
 
+		revoked, err := m.RevocationStore.IsRevoked(ctx, userData.Id, userCtx.UserID, time.Unix(userData.IssuedAt, 0))
+		if err != nil {
+			errResponse.Message = "Unauthorized"
+			errResponse.Error = "Unauthorized"
+			return c.JSON(http.StatusUnauthorized, errResponse)
+		}
+		if revoked {
+			errResponse.Message = "Token has been revoked"
+			return c.JSON(http.StatusUnauthorized, errResponse)
+		}
+
 		user, err := m.UserRepo.GetUserByID(ctx, userCtx.UserID)
 		if err != nil {
 			errResponse.Message = "Unauthorized"
@@ -83,6 +116,10 @@ func (m *MiddleWareImpl) AuthUser(next echo.HandlerFunc) echo.HandlerFunc {
 		userCtx.Email = user.Email
 		userCtx.Username = user.Username
 		userCtx.UserID = user.ID
+		userCtx.Role = user.Role
+		userCtx.EmailVerified = user.EmailVerifiedAt != nil
+		userCtx.TokenID = userData.Id
+		userCtx.TokenExpiresAt = time.Unix(userData.ExpiresAt, 0)
 
 		ctx = context.WithValue(ctx, UserData, userCtx)
 
@@ -91,3 +128,30 @@ func (m *MiddleWareImpl) AuthUser(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+// RequireRole only lets through users holding one of roles. It must run after
+// AuthUser, which loads the role from the database on every request.
+func (m *MiddleWareImpl) RequireRole(roles ...models.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userCtx, ok := c.Request().Context().Value(UserData).(UserCtxReq)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, models.DefaultResponse{
+					Code:    http.StatusUnauthorized,
+					Message: "Unauthorized",
+				})
+			}
+
+			for _, role := range roles {
+				if userCtx.Role == role {
+					return next(c)
+				}
+			}
+
+			return c.JSON(http.StatusForbidden, models.DefaultResponse{
+				Code:    http.StatusForbidden,
+				Message: "Forbidden",
+			})
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Package money represents monetary amounts exactly, as a whole number of
+// minor units (cents, sen) together with an ISO 4217 currency code.
+//
+// Every amount in the shop is stored as DECIMAL(18, 2), so all currencies use
+// a fixed scale of two fractional digits and every stored amount fits in an
+// int64 of minor units. Amounts are never converted to or from float64;
+// parsing rejects anything that cannot be represented exactly, arithmetic
+// that would overflow fails with ErrOverflow, and the only rounding step,
+// Percent, rounds half away from zero.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Scale is the number of fractional digits kept for every currency.
+	Scale = 2
+
+	// DefaultCurrency is used when a request gives an amount without a
+	// currency.
+	DefaultCurrency = "IDR"
+
+	minorPerMajor = 100
+)
+
+var (
+	ErrInvalidAmount    = errors.New("money: invalid amount")
+	ErrTooPrecise       = errors.New("money: amount has more than 2 decimal places")
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	ErrInvalidCurrency  = errors.New("money: currency must be a three letter code")
+	ErrOverflow         = errors.New("money: amount out of range")
+)
+
+type Money struct {
+	// Amount is expressed in minor units, e.g. 1050 is 10.50.
+	Amount   int64
+	Currency string
+}
+
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Parse reads a plain decimal string such as "10000", "-3.5" or "19.99".
+// Exponents, thousands separators and more than Scale fractional digits are
+// rejected rather than rounded.
+func Parse(s, currency string) (m Money, err error) {
+	m.Currency = currency
+
+	value := strings.TrimSpace(s)
+	negative := false
+	switch {
+	case strings.HasPrefix(value, "-"):
+		negative, value = true, value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(value, ".")
+	if whole == "" && fraction == "" || hasPoint && fraction == "" {
+		return m, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if !isDigits(whole) || !isDigits(fraction) {
+		return m, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	// trailing zeros carry no precision, so "1.500" is still exact
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > Scale {
+		return m, fmt.Errorf("%w: %q", ErrTooPrecise, s)
+	}
+	fraction += strings.Repeat("0", Scale-len(fraction))
+
+	if whole == "" {
+		whole = "0"
+	}
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return m, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	if negative {
+		amount = -amount
+	}
+	m.Amount = amount
+	return m, nil
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns m + other. An empty currency on either side takes the other
+// side's currency, so a zero Money can be used as the start of a sum.
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := m.commonCurrency(other)
+	if err != nil {
+		return m, err
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return m, fmt.Errorf("%w: %s + %s", ErrOverflow, m.Decimal(), other.Decimal())
+	}
+	return Money{Amount: sum, Currency: currency}, nil
+}
+
+// Sub returns m - other, with the same currency rules as Add.
+func (m Money) Sub(other Money) (Money, error) {
+	currency, err := m.commonCurrency(other)
+	if err != nil {
+		return m, err
+	}
+	difference := m.Amount - other.Amount
+	if (other.Amount < 0 && difference < m.Amount) || (other.Amount > 0 && difference > m.Amount) {
+		return m, fmt.Errorf("%w: %s - %s", ErrOverflow, m.Decimal(), other.Decimal())
+	}
+	return Money{Amount: difference, Currency: currency}, nil
+}
+
+// Mul multiplies by a whole quantity, which never needs rounding.
+func (m Money) Mul(quantity int64) (Money, error) {
+	product := m.Amount * quantity
+	if quantity != 0 && (product/quantity != m.Amount || (quantity == -1 && m.Amount == math.MinInt64)) {
+		return m, fmt.Errorf("%w: %s * %d", ErrOverflow, m.Decimal(), quantity)
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Percent returns percent% of m, rounded to the nearest minor unit with
+// halves rounded away from zero: 5% of 0.10 is 0.01 and 5% of 0.30 is 0.02.
+func (m Money) Percent(percent int64) (Money, error) {
+	product, err := m.Mul(percent)
+	if err != nil {
+		return m, err
+	}
+	amount, remainder := product.Amount/100, product.Amount%100
+	switch {
+	case remainder >= 50:
+		amount++
+	case remainder <= -50:
+		amount--
+	}
+	return Money{Amount: amount, Currency: m.Currency}, nil
+}
+
+// Cmp compares m with other and returns -1, 0 or +1. Amounts in different
+// currencies are not comparable.
+func (m Money) Cmp(other Money) (int, error) {
+	if _, err := m.commonCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Equal reports whether both amount and currency match exactly.
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && m.Currency == other.Currency
+}
+
+// OrDefault fills in DefaultCurrency when m has no currency.
+func (m Money) OrDefault() Money {
+	if m.Currency == "" {
+		m.Currency = DefaultCurrency
+	}
+	return m
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) commonCurrency(other Money) (string, error) {
+	switch {
+	case m.Currency == "":
+		return other.Currency, nil
+	case other.Currency == "", m.Currency == other.Currency:
+		return m.Currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+}
+
+// Decimal formats the amount without its currency, e.g. "10000.00".
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/minorPerMajor, amount%minorPerMajor)
+}
+
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Decimal()
+	}
+	return m.Currency + " " + m.Decimal()
+}
+
+// MarshalJSON writes {"amount":"10000.00","currency":"IDR"}. The amount is a
+// string so clients never read it into a binary float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{
+		Amount:   m.Decimal(),
+		Currency: m.Currency,
+	})
+}
+
+// UnmarshalJSON accepts the object written by MarshalJSON, where the amount
+// may be a string or a number, as well as a bare number or string such as
+// 10000.5 or "10000.50". A bare amount leaves the currency empty.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" {
+		return nil
+	}
+
+	currency := ""
+	if strings.HasPrefix(raw, "{") {
+		var object jsonMoney
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		if len(object.Amount) == 0 {
+			return fmt.Errorf("%w: missing amount", ErrInvalidAmount)
+		}
+		raw = string(object.Amount)
+		currency = strings.ToUpper(object.Currency)
+		if currency != "" && !isCurrencyCode(currency) {
+			return fmt.Errorf("%w: %q", ErrInvalidCurrency, object.Currency)
+		}
+	}
+
+	if strings.HasPrefix(raw, `"`) {
+		var value string
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return err
+		}
+		raw = value
+	}
+
+	parsed, err := Parse(raw, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads a DECIMAL column. Only the amount is stored in that column, so
+// the currency already set on m is kept.
+func (m *Money) Scan(src interface{}) error {
+	var (
+		parsed Money
+		err    error
+	)
+	switch value := src.(type) {
+	case nil:
+		m.Amount = 0
+		return nil
+	case []byte:
+		parsed, err = Parse(string(value), m.Currency)
+	case string:
+		parsed, err = Parse(value, m.Currency)
+	case int64:
+		parsed = Money{Amount: value * minorPerMajor, Currency: m.Currency}
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	if err != nil {
+		return err
+	}
+	m.Amount = parsed.Amount
+	return nil
+}
+
+// Value writes the amount as a decimal string for a DECIMAL column.
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
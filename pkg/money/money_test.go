@@ -0,0 +1,251 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr error
+	}{
+		{in: "10000", want: 1000000},
+		{in: "19.99", want: 1999},
+		{in: "-3.5", want: -350},
+		{in: "+1", want: 100},
+		{in: ".5", want: 50},
+		{in: "0.01", want: 1},
+		{in: "1.500", want: 150},
+		{in: " 7 ", want: 700},
+		{in: "1.234", wantErr: ErrTooPrecise},
+		{in: "", wantErr: ErrInvalidAmount},
+		{in: "-", wantErr: ErrInvalidAmount},
+		{in: "1.", wantErr: ErrInvalidAmount},
+		{in: "1e5", wantErr: ErrInvalidAmount},
+		{in: "1,000", wantErr: ErrInvalidAmount},
+		{in: "--1", wantErr: ErrInvalidAmount},
+		{in: "abc", wantErr: ErrInvalidAmount},
+		{in: "99999999999999999999", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in, "IDR")
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != New(tt.want, "IDR") {
+			t.Errorf("Parse(%q) = %+v, want amount %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPercentRoundsHalfAwayFromZero(t *testing.T) {
+	tests := []struct {
+		amount  int64
+		percent int64
+		want    int64
+	}{
+		{amount: 10, percent: 5, want: 1},
+		{amount: 30, percent: 5, want: 2},
+		{amount: 4, percent: 10, want: 0},
+		{amount: 1, percent: 50, want: 1},
+		{amount: 1, percent: 49, want: 0},
+		{amount: -10, percent: 5, want: -1},
+		{amount: -1, percent: 50, want: -1},
+		{amount: 1000000, percent: 100, want: 1000000},
+		{amount: 1000000, percent: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		got, err := New(tt.amount, "IDR").Percent(tt.percent)
+		if err != nil || got != New(tt.want, "IDR") {
+			t.Errorf("%d%% of %d = %+v, %v, want %d", tt.percent, tt.amount, got, err, tt.want)
+		}
+	}
+}
+
+func TestAddSubCurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		sum     Money
+		diff    Money
+		wantErr error
+	}{
+		{name: "same currency", a: New(150, "IDR"), b: New(50, "IDR"), sum: New(200, "IDR"), diff: New(100, "IDR")},
+		{name: "empty left takes right", a: Money{}, b: New(50, "IDR"), sum: New(50, "IDR"), diff: New(-50, "IDR")},
+		{name: "empty right takes left", a: New(50, "USD"), b: Money{}, sum: New(50, "USD"), diff: New(50, "USD")},
+		{name: "mismatch", a: New(50, "IDR"), b: New(50, "USD"), wantErr: ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		sum, err := tt.a.Add(tt.b)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Add error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		diff, subErr := tt.a.Sub(tt.b)
+		if !errors.Is(subErr, tt.wantErr) {
+			t.Errorf("%s: Sub error = %v, want %v", tt.name, subErr, tt.wantErr)
+		}
+		if tt.wantErr != nil {
+			continue
+		}
+		if sum != tt.sum {
+			t.Errorf("%s: Add = %+v, want %+v", tt.name, sum, tt.sum)
+		}
+		if diff != tt.diff {
+			t.Errorf("%s: Sub = %+v, want %+v", tt.name, diff, tt.diff)
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	max := New(math.MaxInt64, "IDR")
+	min := New(math.MinInt64, "IDR")
+	one := New(1, "IDR")
+
+	if _, err := max.Add(one); !errors.Is(err, ErrOverflow) {
+		t.Errorf("max + 1 error = %v, want ErrOverflow", err)
+	}
+	if _, err := min.Add(New(-1, "IDR")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("min + -1 error = %v, want ErrOverflow", err)
+	}
+	if _, err := min.Sub(one); !errors.Is(err, ErrOverflow) {
+		t.Errorf("min - 1 error = %v, want ErrOverflow", err)
+	}
+	if _, err := max.Sub(New(-1, "IDR")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("max - -1 error = %v, want ErrOverflow", err)
+	}
+	if _, err := max.Mul(2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("max * 2 error = %v, want ErrOverflow", err)
+	}
+	if _, err := min.Mul(-1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("min * -1 error = %v, want ErrOverflow", err)
+	}
+	if _, err := max.Percent(2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("2%% of max error = %v, want ErrOverflow", err)
+	}
+	if _, err := New(math.MaxInt64/50, "IDR").Percent(51); !errors.Is(err, ErrOverflow) {
+		t.Errorf("51%% of max/50 error = %v, want ErrOverflow", err)
+	}
+
+	got, err := New(1999, "IDR").Mul(3)
+	if err != nil || got != New(5997, "IDR") {
+		t.Errorf("19.99 * 3 = %+v, %v, want 59.97", got, err)
+	}
+	got, err = max.Mul(0)
+	if err != nil || got != New(0, "IDR") {
+		t.Errorf("max * 0 = %+v, %v, want 0", got, err)
+	}
+	got, err = max.Percent(1)
+	if err != nil || got != New(math.MaxInt64/100, "IDR") {
+		t.Errorf("1%% of max = %+v, %v, want %d", got, err, int64(math.MaxInt64/100))
+	}
+	got, err = New(math.MaxInt64/100, "IDR").Percent(100)
+	if err != nil || got != New(math.MaxInt64/100, "IDR") {
+		t.Errorf("100%% of max/100 = %+v, %v", got, err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	data, err := json.Marshal(New(1000050, "IDR"))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != `{"amount":"10000.50","currency":"IDR"}` {
+		t.Errorf("Marshal = %s", data)
+	}
+
+	var back Money
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if back != New(1000050, "IDR") {
+		t.Errorf("round trip = %+v", back)
+	}
+
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `{"amount":10000.5,"currency":"idr"}`, want: New(1000050, "IDR")},
+		{in: `10000.5`, want: New(1000050, "")},
+		{in: `"-0.25"`, want: New(-25, "")},
+		{in: `{"amount":"1.001","currency":"IDR"}`, wantErr: true},
+		{in: `{"amount":"1","currency":"RUPIAH"}`, wantErr: true},
+		{in: `{"currency":"IDR"}`, wantErr: true},
+		{in: `1e3`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %+v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+
+	got := New(5, "IDR")
+	if err := json.Unmarshal([]byte(`null`), &got); err != nil || got != New(5, "IDR") {
+		t.Errorf("Unmarshal(null) = %+v, %v, want unchanged", got, err)
+	}
+}
+
+func TestScanValueRoundTrip(t *testing.T) {
+	for _, m := range []Money{New(12345, "IDR"), New(-5, "IDR"), New(0, "IDR"), New(math.MaxInt64, "IDR")} {
+		value, err := m.Value()
+		if err != nil {
+			t.Fatalf("Value(%+v): %v", m, err)
+		}
+
+		back := Money{Currency: "IDR"}
+		if err := back.Scan([]byte(value.(string))); err != nil {
+			t.Fatalf("Scan(%v): %v", value, err)
+		}
+		if back != m {
+			t.Errorf("Scan(Value(%+v)) = %+v", m, back)
+		}
+	}
+
+	tests := []struct {
+		src     interface{}
+		want    Money
+		wantErr bool
+	}{
+		{src: "10.50", want: New(1050, "USD")},
+		{src: int64(7), want: New(700, "USD")},
+		{src: nil, want: New(0, "USD")},
+		{src: "10.505", wantErr: true},
+		{src: 10.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		got := New(99, "USD")
+		err := got.Scan(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%v) = %+v, want error", tt.src, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Scan(%v) = %+v, %v, want %+v", tt.src, got, err, tt.want)
+		}
+	}
+}
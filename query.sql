@@ -1,15 +1,75 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(255) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
     email VARCHAR(255) NOT NULL UNIQUE,
+    role VARCHAR(20) NOT NULL DEFAULT 'customer' CHECK (role IN ('customer', 'staff', 'admin')),
+    tokens_revoked_before TIMESTAMPTZ,
+    email_verified_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    family_id VARCHAR(64) NOT NULL,
+    parent_id INTEGER,
+    replaced_by INTEGER,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_id) REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    FOREIGN KEY (replaced_by) REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    purpose VARCHAR(30) NOT NULL CHECK (purpose IN ('password_reset', 'email_verification', 'email_change')),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    new_email VARCHAR(255),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_addresses (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    label VARCHAR(50) NOT NULL DEFAULT '',
+    recipient_name VARCHAR(255) NOT NULL,
+    phone VARCHAR(30) NOT NULL,
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL,
+    province VARCHAR(100) NOT NULL,
+    postal_code VARCHAR(20) NOT NULL,
+    country CHAR(2) NOT NULL DEFAULT 'ID',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE categories (
     id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL UNIQUE,
+    parent_id INTEGER,
+    name VARCHAR(255) NOT NULL,
+    FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE RESTRICT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -17,19 +77,65 @@ CREATE TABLE categories (
 CREATE TABLE products (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
     category_id INTEGER NOT NULL,
-    price DECIMAL(10, 2) NOT NULL,
-    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
+    price DECIMAL(18, 2) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    search_vector TSVECTOR,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP
 );
 
+CREATE TABLE stock_adjustments (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    delta INTEGER NOT NULL,
+    stock_after INTEGER NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE promotions (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+    discount_percent INTEGER NOT NULL DEFAULT 0 CHECK (discount_percent BETWEEN 0 AND 100),
+    discount_amount DECIMAL(18, 2) NOT NULL DEFAULT 0,
+    max_discount DECIMAL(18, 2),
+    min_spend DECIMAL(18, 2) NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    usage_limit INTEGER,
+    per_user_limit INTEGER,
+    used_count INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ends_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE promotion_scopes (
+    id SERIAL PRIMARY KEY,
+    promotion_id INTEGER NOT NULL,
+    product_id INTEGER,
+    category_id INTEGER,
+    CHECK ((product_id IS NULL) <> (category_id IS NULL)),
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
 CREATE TABLE cart_items (
     id SERIAL PRIMARY KEY,
     product_id INTEGER NOT NULL,
     user_id INTEGER NOT NULL,
     quantity INTEGER NOT NULL,
+    price_at_add DECIMAL(18, 2),
     FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
     UNIQUE(product_id, user_id),
@@ -37,13 +143,30 @@ CREATE TABLE cart_items (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE cart_coupons (
+    user_id INTEGER PRIMARY KEY,
+    promotion_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE orders (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL,
-    total_amount DECIMAL(10, 2) NOT NULL,
+    subtotal_amount DECIMAL(18, 2) NOT NULL DEFAULT 0,
+    discount_amount DECIMAL(18, 2) NOT NULL DEFAULT 0,
+    total_amount DECIMAL(18, 2) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
     order_code VARCHAR(50) NOT NULL,
-    status VARCHAR(50) NOT NULL DEFAULT 'Pending',
+    status VARCHAR(50) NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending', 'Paid', 'Processing', 'Shipped', 'Delivered', 'Cancelled', 'Expired', 'RefundPending', 'Refunded')),
+    expires_at TIMESTAMPTZ,
+    payment_provider VARCHAR(50) NOT NULL DEFAULT '',
+    payment_reference VARCHAR(100) NOT NULL DEFAULT '',
+    promotion_id INTEGER,
+    shipping_address JSONB,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id) ON DELETE SET NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -53,22 +176,134 @@ CREATE TABLE order_items (
     order_id INTEGER NOT NULL,
     product_id INTEGER NOT NULL,
     quantity INTEGER NOT NULL,
-    price DECIMAL(10, 2) NOT NULL,
+    price DECIMAL(18, 2) NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE promotion_redemptions (
+    id SERIAL PRIMARY KEY,
+    promotion_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    order_id INTEGER NOT NULL UNIQUE,
+    discount_amount DECIMAL(18, 2) NOT NULL,
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    changed_by INTEGER,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE refunds (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL UNIQUE,
+    amount DECIMAL(18, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'Pending',
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    provider_reference VARCHAR(100) NOT NULL DEFAULT '',
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE stock_reservations (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'Reserved',
+    expires_at TIMESTAMPTZ NOT NULL,
     FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
     FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    response_code INTEGER,
+    response_body BYTEA,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, idempotency_key, endpoint),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ
+);
+
+
+-- TRIGGERS
+-- products.search_vector weights the name over the category name over the
+-- description; it is rebuilt whenever any of them changes.
+CREATE FUNCTION products_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', COALESCE(NEW.name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE((SELECT name FROM categories WHERE id = NEW.category_id), '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(NEW.description, '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_products_search_vector
+BEFORE INSERT OR UPDATE OF name, description, category_id ON products
+FOR EACH ROW EXECUTE FUNCTION products_search_vector_update();
+
+CREATE FUNCTION categories_search_vector_refresh() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE products SET name = name WHERE category_id = NEW.id;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_categories_search_vector
+AFTER UPDATE OF name ON categories
+FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+EXECUTE FUNCTION categories_search_vector_refresh();
+
 
 -- INDEXES
+CREATE UNIQUE INDEX idx_category_parent_name ON categories USING btree(COALESCE(parent_id, 0), LOWER(name));
+CREATE INDEX idx_category_parent_id ON categories USING btree(parent_id);
 CREATE INDEX idx_category ON products USING btree(category_id);
+CREATE INDEX idx_product_price ON products USING btree(price);
+CREATE INDEX idx_product_created_at ON products USING btree(created_at);
+CREATE INDEX idx_product_search_vector ON products USING gin(search_vector);
+CREATE INDEX idx_product_name_trgm ON products USING gin(name gin_trgm_ops);
 CREATE INDEX idx_product_user_id ON cart_items USING btree(user_id);
 CREATE INDEX idx_product_id ON cart_items USING btree(product_id);
 CREATE INDEX idx_order_user_id ON orders USING btree(user_id);
 CREATE INDEX idx_order_id ON order_items USING btree(order_id);
 CREATE INDEX idx_order_product_id ON order_items USING btree(product_id);
 CREATE INDEX idx_order_code ON orders USING btree(order_code);
+CREATE INDEX idx_order_status_expires_at ON orders USING btree(status, expires_at);
+CREATE INDEX idx_order_status_history_order_id ON order_status_history USING btree(order_id);
+CREATE INDEX idx_stock_reservation_order_id ON stock_reservations USING btree(order_id);
+CREATE INDEX idx_stock_adjustment_product_id ON stock_adjustments USING btree(product_id);
+CREATE INDEX idx_promotion_scope_promotion_id ON promotion_scopes USING btree(promotion_id);
+CREATE INDEX idx_promotion_redemption_promotion_user ON promotion_redemptions USING btree(promotion_id, user_id);
+CREATE INDEX idx_refresh_token_family_id ON refresh_tokens USING btree(family_id);
+CREATE INDEX idx_refresh_token_user_id ON refresh_tokens USING btree(user_id);
+CREATE INDEX idx_user_address_user_id ON user_addresses USING btree(user_id);
+CREATE UNIQUE INDEX idx_user_address_default ON user_addresses USING btree(user_id) WHERE is_default;
+CREATE INDEX idx_user_token_user_purpose ON user_tokens USING btree(user_id, purpose);
+CREATE INDEX idx_revoked_token_expires_at ON revoked_tokens USING btree(expires_at);
+CREATE INDEX idx_idempotency_key_created_at ON idempotency_keys USING btree(created_at);
 
 
 
@@ -81,25 +316,25 @@ INSERT INTO categories (name) VALUES
 ('Toys');
 
 
-INSERT INTO products (name, category_id, price) 
+INSERT INTO products (name, category_id, price, stock) 
 VALUES
-('iPhone 12', 1, 10000000.00),
-('Samsung Galaxy S21', 1, 9000000.00),
-('Macbook Pro', 1, 20000000.00),
-('Dell XPS 15', 1, 15000000.00),
-('Nike Air Max', 2, 500000.00),
-('Adidas Superstar', 2, 400000.00),
-('Levi''s Jeans', 2, 300000.00),
-('H&M T-shirt', 2, 200000.00),
-('The Alchemist', 3, 100000.00),
-('Harry Potter', 3, 150000.00),
-('The Da Vinci Code', 3, 120000.00),
-('The Great Gatsby', 3, 110000.00),
-('Sofa', 4, 3000000.00),
-('Dining Table', 4, 2500000.00),
-('Bed', 4, 2000000.00),
-('Wardrobe', 4, 1500000.00),
-('Lego', 5, 1000000.00),
-('Barbie', 5, 800000.00),
-('Hot Wheels', 5, 700000.00),
-('Nerf', 5, 600000.00);
\ No newline at end of file
+('iPhone 12', 1, 10000000.00, 100),
+('Samsung Galaxy S21', 1, 9000000.00, 100),
+('Macbook Pro', 1, 20000000.00, 100),
+('Dell XPS 15', 1, 15000000.00, 100),
+('Nike Air Max', 2, 500000.00, 100),
+('Adidas Superstar', 2, 400000.00, 100),
+('Levi''s Jeans', 2, 300000.00, 100),
+('H&M T-shirt', 2, 200000.00, 100),
+('The Alchemist', 3, 100000.00, 100),
+('Harry Potter', 3, 150000.00, 100),
+('The Da Vinci Code', 3, 120000.00, 100),
+('The Great Gatsby', 3, 110000.00, 100),
+('Sofa', 4, 3000000.00, 100),
+('Dining Table', 4, 2500000.00, 100),
+('Bed', 4, 2000000.00, 100),
+('Wardrobe', 4, 1500000.00, 100),
+('Lego', 5, 1000000.00, 100),
+('Barbie', 5, 800000.00, 100),
+('Hot Wheels', 5, 700000.00, 100),
+('Nerf', 5, 600000.00, 100);
\ No newline at end of file